@@ -0,0 +1,168 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import "strconv"
+
+// ErrorKind is the kind of a SyntaxError.
+type ErrorKind int
+
+// Kinds of syntax errors.
+const (
+	_                   ErrorKind = iota
+	UnexpectedChar                // unexpected character
+	UnterminatedString            // string is missing its closing quote
+	BadEscape                     // invalid escape sequence in a string
+	BadNumber                     // invalid number
+	TrailingData                  // data found after the end of the document
+	UnexpectedEOF                 // document ended before it was complete
+	UnterminatedComment           // block comment is missing its "*/"
+	DepthExceeded                 // nesting depth exceeds the maximum
+	InvalidUTF8                   // invalid UTF-8 in a string (see ValidateUTF8)
+	BadSurrogate                  // lone surrogate escape (see RejectSurrogates)
+	DuplicateKey                  // repeated object key (see RejectDuplicates)
+	DocumentTooLarge              // document exceeds Limits.MaxBytes
+	TooManyTokens                 // document exceeds Limits.MaxTokens
+	StringTooLong                 // string or key exceeds Limits.MaxStringLen
+	NumberTooLong                 // number exceeds Limits.MaxNumberLen
+	TooManyMembers                // object or array exceeds Limits.MaxMembers
+)
+
+var kindNames = [...]string{
+	UnexpectedChar:      "unexpected character",
+	UnterminatedString:  "unterminated string",
+	BadEscape:           "invalid escape sequence",
+	BadNumber:           "invalid number",
+	TrailingData:        "unexpected data after the document",
+	UnexpectedEOF:       "unexpected end of document",
+	UnterminatedComment: "unterminated comment",
+	DepthExceeded:       "nesting depth exceeded",
+	InvalidUTF8:         "invalid UTF-8",
+	BadSurrogate:        "lone surrogate escape",
+	DuplicateKey:        "duplicate key",
+	DocumentTooLarge:    "document too large",
+	TooManyTokens:       "too many tokens",
+	StringTooLong:       "string too long",
+	NumberTooLong:       "number too long",
+	TooManyMembers:      "too many members",
+}
+
+func (kind ErrorKind) String() string {
+	if kind > 0 && int(kind) < len(kindNames) {
+		return kindNames[kind]
+	}
+	return "ErrorKind(" + strconv.Itoa(int(kind)) + ")"
+}
+
+// SyntaxError describes why and where a JSON document is invalid.
+type SyntaxError struct {
+	Kind    ErrorKind // kind of error
+	Offset  int       // byte offset of the error in the document
+	Line    int       // 1-based line number of the error
+	Column  int       // 1-based column (in bytes) of the error
+	Char    byte      // offending byte, or zero if there is none
+	Context string    // the document data surrounding the error
+}
+
+func (e *SyntaxError) Error() string {
+	msg := "pjson: " + e.Kind.String()
+	if !e.incomplete() && e.Kind != DocumentTooLarge {
+		if e.Char < 0x80 {
+			msg += " " + strconv.QuoteRune(rune(e.Char))
+		} else {
+			msg += ` '\x` + strconv.FormatUint(uint64(e.Char), 16) + `'`
+		}
+	}
+	return msg + " at line " + strconv.Itoa(e.Line) +
+		", column " + strconv.Itoa(e.Column) +
+		" (offset " + strconv.Itoa(e.Offset) + ")"
+}
+
+// Is returns true when the target is ErrIncomplete and the document ended
+// before it was complete, or when the target is ErrInvalid and the document
+// has invalid data.
+func (e *SyntaxError) Is(target error) bool {
+	switch target {
+	case ErrIncomplete:
+		return e.incomplete()
+	case ErrInvalid:
+		return !e.incomplete()
+	}
+	return false
+}
+
+func (e *SyntaxError) incomplete() bool {
+	return e.Kind == UnexpectedEOF || e.Kind == UnterminatedString ||
+		e.Kind == UnterminatedComment
+}
+
+// newlines returns the number of newlines in json and the index of the last
+// one, or -1 if there are none.
+func newlines[T Input](json T) (n, last int) {
+	last = -1
+	for i := 0; i < len(json); i++ {
+		if json[i] == '\n' {
+			n++
+			last = i
+		}
+	}
+	return n, last
+}
+
+// contextSize is the maximum number of bytes on either side of an error that
+// are included in the SyntaxError context.
+const contextSize = 16
+
+// newSyntaxError returns a SyntaxError for the error at json[i], where
+// 'base' is the offset of json[0] in the document, and 'line' and 'lineAt'
+// are the 0-based line and the offset of the line that contains json[0].
+func newSyntaxError[T Input](kind ErrorKind, json T, i, base, line,
+	lineAt int,
+) *SyntaxError {
+	n, j := newlines(json[:i])
+	line += n
+	if j >= 0 {
+		lineAt = base + j + 1
+	}
+	e := &SyntaxError{
+		Kind:   kind,
+		Offset: base + i,
+		Line:   line + 1,
+		Column: base + i - lineAt + 1,
+	}
+	if i < len(json) {
+		e.Char = json[i]
+	}
+	s, t := i-contextSize, i+contextSize
+	if s < 0 {
+		s = 0
+	}
+	if t > len(json) {
+		t = len(json)
+	}
+	e.Context = string(json[s:t])
+	return e
+}
+
+// ParseErr parses JSON like Parse, but it returns a *SyntaxError when the
+// JSON document is invalid.
+// The returned value is the position that the parser was at when it finished,
+// when the 'iter' function stopped the parsing, or when it discovered the
+// error, like Parse. The Offset of the error is the same position, except
+// for a bad literal, such as "trux", where the position is after the first
+// byte and the Offset is at the first byte that does not match.
+func ParseErr[T Input](json T, opts int, iter func(start, end, info int) int,
+) (int, error) {
+	return ParseLimits(json, opts, Limits{}, iter)
+}
+
+// syntaxErr scans an invalid document again with the stream to find out why
+// vdoc failed, and returns the position and the error for it.
+func syntaxErr[T Input](json T, limits Limits) (int, error) {
+	var s stream[T]
+	s.limits = limits
+	s.parse(json, 0)
+	return s.i, s.err()
+}
@@ -0,0 +1,126 @@
+package pjson
+
+import (
+	"errors"
+	"testing"
+)
+
+func testParseErr(t *testing.T, json string, kind ErrorKind,
+	offset, line, column int,
+) {
+	t.Helper()
+	n, err := ParseErr([]byte(json), 0, nil)
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("%q: expected a syntax error, got %v", json, err)
+	}
+	if serr.Kind != kind || serr.Offset != offset || n != offset ||
+		serr.Line != line || serr.Column != column {
+		t.Fatalf("%q: expected %v at %d (%d:%d), got %v at %d (%d:%d)",
+			json, kind, offset, line, column, serr.Kind, serr.Offset,
+			serr.Line, serr.Column)
+	}
+	if e := Parse([]byte(json), 0, nil); e != -offset {
+		t.Fatalf("%q: expected Parse to return %d, got %d", json, -offset, e)
+	}
+	if offset < len(json) && serr.Char != json[offset] {
+		t.Fatalf("%q: expected char %q, got %q", json, json[offset], serr.Char)
+	}
+}
+
+func TestParseErr(t *testing.T) {
+	testParseErr(t, ``, UnexpectedEOF, 0, 1, 1)
+	testParseErr(t, `  `, UnexpectedEOF, 2, 1, 3)
+	testParseErr(t, `x`, UnexpectedChar, 0, 1, 1)
+	testParseErr(t, "[\n  1,\n  x]", UnexpectedChar, 9, 3, 3)
+	testParseErr(t, "{\n\"a\" 1}", UnexpectedChar, 6, 2, 5)
+	testParseErr(t, `{"a":1 "b":2}`, UnexpectedChar, 7, 1, 8)
+	testParseErr(t, `{"a":1,}`, UnexpectedChar, 7, 1, 8)
+	testParseErr(t, `{1:2}`, UnexpectedChar, 1, 1, 2)
+	testParseErr(t, `"hello`, UnterminatedString, 6, 1, 7)
+	testParseErr(t, `["a\u00`, UnterminatedString, 7, 1, 8)
+	testParseErr(t, "\"a\x01\"", UnexpectedChar, 2, 1, 3)
+	testParseErr(t, `"a\x"`, BadEscape, 3, 1, 4)
+	testParseErr(t, `"a\u00x0"`, BadEscape, 6, 1, 7)
+	testParseErr(t, `01`, TrailingData, 1, 1, 2)
+	testParseErr(t, `-a`, BadNumber, 1, 1, 2)
+	testParseErr(t, `1.e`, BadNumber, 2, 1, 3)
+	testParseErr(t, `[1.`, UnexpectedEOF, 3, 1, 4)
+	testParseErr(t, `[1,`, UnexpectedEOF, 3, 1, 4)
+	testParseErr(t, "{}\n\n  {}", TrailingData, 6, 3, 3)
+
+	// The error of a bad literal is at the first byte that does not match,
+	// but the returned position is after the first byte, like Parse.
+	for _, lit := range []struct {
+		json           string
+		kind           ErrorKind
+		offset, column int
+		n              int
+	}{
+		{`trux`, UnexpectedChar, 3, 4, 1},
+		{`[nul`, UnexpectedEOF, 4, 5, 2},
+		{`[fals0]`, UnexpectedChar, 5, 6, 2},
+		{"[\n  nx", UnexpectedChar, 5, 4, 5},
+	} {
+		n, err := ParseErr([]byte(lit.json), 0, nil)
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || serr.Kind != lit.kind ||
+			serr.Offset != lit.offset || serr.Column != lit.column ||
+			n != lit.n {
+			t.Fatalf("%q: expected %v at %d (column %d), got %d %v",
+				lit.json, lit.kind, lit.offset, lit.column, n, err)
+		}
+		if e := Parse([]byte(lit.json), 0, nil); e != -lit.n {
+			t.Fatalf("%q: expected Parse to return %d, got %d", lit.json,
+				-lit.n, e)
+		}
+	}
+
+	n, err := ParseErr([]byte(`{"a":1}`), 0, func(start, end, info int) int {
+		return 0
+	})
+	if n != 0 || err != nil {
+		t.Fatalf("expected 0 and nil, got %d and %v", n, err)
+	}
+	n, err = ParseErr([]byte(` {"a":1} `), 0, nil)
+	if n != 9 || err != nil {
+		t.Fatalf("expected 9 and nil, got %d and %v", n, err)
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := ParseErr([]byte(`{"hello":"world","x":tru,"y":false}`), 0, nil)
+	if err.Error() != `pjson: unexpected character ',' at line 1, `+
+		`column 25 (offset 24)` {
+		t.Fatalf("unexpected message: %s", err)
+	}
+	if err.(*SyntaxError).Context != `:"world","x":tru,"y":false}` {
+		t.Fatalf("unexpected context: %q", err.(*SyntaxError).Context)
+	}
+	if !errors.Is(err, ErrInvalid) || errors.Is(err, ErrIncomplete) {
+		t.Fatal("expected ErrInvalid")
+	}
+	_, err = ParseErr([]byte(`{"hello":"wor`), 0, nil)
+	if err.Error() != `pjson: unterminated string at line 1, `+
+		`column 14 (offset 13)` {
+		t.Fatalf("unexpected message: %s", err)
+	}
+	if errors.Is(err, ErrInvalid) || !errors.Is(err, ErrIncomplete) {
+		t.Fatal("expected ErrIncomplete")
+	}
+}
+
+func TestParserSyntaxError(t *testing.T) {
+	p := NewParser(0, nil)
+	for _, chunk := range []string{"[\n", "1,\n2", "\n,3,\n", "4 5]"} {
+		if _, err := p.Write([]byte(chunk)); err != nil {
+			serr := err.(*SyntaxError)
+			if serr.Offset != 13 || serr.Line != 5 || serr.Column != 3 ||
+				serr.Kind != UnexpectedChar || serr.Char != '5' {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			return
+		}
+	}
+	t.Fatal("expected an error")
+}
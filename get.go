@@ -0,0 +1,326 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+// Get returns the value at a path in a JSON document, such that
+// json[start:end] is the complete value, such as a String with its quotes or
+// an entire Object.
+// A path is a series of keys that are separated by dots. A key that is a
+// number, without leading zeros, is the index of an element when the value at
+// that point of the path is an Array. A '\' escapes the next character of a
+// key, such that the path `fav\.movie` is the key "fav.movie". An empty path
+// is the entire document.
+//
+//	start, end, info := pjson.Get(json, "friends.1.first")
+//	if info != 0 {
+//	    fmt.Printf("%s\n", json[start:end]) // "Roger"
+//	}
+//
+// The 'info' is zero when the path is not found, or when the document is
+// invalid before the value is found. Otherwise it's the info of the value,
+// which for Objects and Arrays only has the Object or Array bit, and the
+// Value bit, like ParseObject. The entire document has no Value bit, like
+// the root value of Parse, such that an Object document is only Object.
+// The Objects and Arrays that are not on the path are skipped, like returning
+// -1 from the 'iter' function of Parse, and the parsing stops at the value,
+// such that the rest of the document is not validated.
+func Get[T Input](json T, path string) (start, end, info int) {
+	start, end, info, _ = get(json, path, path == "", pathkey)
+	return start, end, info
+}
+
+// get returns the value at a path, where 'next' returns the first key of the
+// path, and 'root' is true for a path without keys. It returns false if the
+// document is invalid before the value is found, or found to be missing.
+func get[T Input](json T, path string, root bool,
+	next func(path string) (key, rest string, index int, more bool),
+) (start, end, info int, ok bool) {
+	var (
+		key     string // path key for the current container
+		last    = root // the key is the last key of the path
+		inarr   bool   // the current container is an Array
+		index   int    // index of the path key, in an Array
+		n       int    // index of the next element, in an Array
+		match   bool   // the last key of the Object matches
+		skipped bool   // the last element was a skipped Open
+		mark    = -1   // start of the value, for Objects and Arrays
+	)
+	// The iter function does not escape from vdoc, so it's not allocated.
+	_, ok, _ = vdoc(json, 0, func(s, e, inf int) int {
+		switch {
+		case inf&Close == Close:
+			if !skipped {
+				// The end of a container on the path.
+				return 0
+			}
+			skipped = false
+			if mark >= 0 {
+				start, end = mark, e
+				return 0
+			}
+			return 1
+		case inf&Key == Key:
+			match = keyeq(json[s:e], inf, key)
+			return 1
+		case inf&(Start|Value) == 0:
+			// Comma or Colon
+			return 1
+		}
+		target := match
+		if inf&Start == Start {
+			target = true
+		} else if inarr {
+			target = n == index
+			n++
+		}
+		if !target {
+			if inf&Open == Open {
+				skipped = true
+				return -1
+			}
+			return 1
+		}
+		if last {
+			info = inf &^ (Start | End | Open)
+			if inf&Open == Open {
+				mark = s
+				skipped = true
+				return -1
+			}
+			start, end = s, e
+			return 0
+		}
+		if inf&Open == 0 {
+			return 0
+		}
+		var more bool
+		key, path, index, more = next(path)
+		last = !more
+		inarr = inf&Array == Array
+		match = false
+		if inarr {
+			n = 0
+			if index < 0 {
+				return 0
+			}
+		}
+		return 1
+	}, DefaultMaxDepth)
+	if end == 0 {
+		return 0, 0, 0, ok
+	}
+	return start, end, info, ok
+}
+
+// Result is the value for a path from GetMany, where the fields are the same
+// as the return values of Get.
+type Result struct {
+	Start int // start index of the value
+	End   int // end index of the value, such that json[Start:End]
+	Info  int // info of the value, or zero if the path is not found
+}
+
+// GetMany returns the values at multiple paths in a JSON document, with one
+// Result for each path. It works like calling Get for each path, but the
+// document is only parsed once.
+// The Objects and Arrays that are not on any path are skipped, and the
+// parsing stops once all of the paths are found.
+func GetMany[T Input](json T, paths ...string) []Result {
+	results := make([]Result, len(paths))
+	if len(paths) == 0 {
+		return results
+	}
+	// Make a trie of the paths, where each node is a path key.
+	var root pathNode
+	for i, path := range paths {
+		node := &root
+		for more := path != ""; more; {
+			var key string
+			var index int
+			key, path, index, more = pathkey(path)
+			node = node.add(key, index)
+		}
+		node.paths = append(node.paths, i)
+	}
+	remain := len(paths)
+	resolve := func(node *pathNode, start, end, info int) {
+		for _, i := range node.paths {
+			results[i] = Result{start, end, info}
+		}
+		remain -= len(node.paths)
+	}
+	var stack []getFrame // open containers
+	var match *pathNode  // node for the key of the next value, if any
+	vdoc(json, 0, func(start, end, info int) int {
+		switch {
+		case info&Key == Key:
+			match = keynode(stack[len(stack)-1].node, json[start:end], info)
+			return 1
+		case info&Close == Close:
+			frame := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if frame.node != nil && len(frame.node.paths) > 0 {
+				resolve(frame.node, frame.start, end, frame.info)
+			}
+		case info&(Start|Value) == 0:
+			// Comma or Colon
+			return 1
+		default:
+			var node *pathNode
+			if info&Start == Start {
+				node = &root
+			} else if frame := &stack[len(stack)-1]; frame.info&Array == Array {
+				node = frame.node.elem(frame.n)
+				frame.n++
+			} else {
+				node, match = match, nil
+			}
+			if info&Open == Open {
+				stack = append(stack, getFrame{node: node, start: start,
+					info: info &^ (Start | Open)})
+				if node == nil || len(node.children) == 0 {
+					return -1
+				}
+				return 1
+			}
+			if node != nil && len(node.paths) > 0 {
+				resolve(node, start, end, info&^(Start|End))
+			}
+		}
+		if remain == 0 {
+			return 0
+		}
+		return 1
+	}, DefaultMaxDepth)
+	return results
+}
+
+// pathNode is a node in a trie of paths for GetMany.
+type pathNode struct {
+	key      string      // unescaped path key
+	index    int         // Array index for the key, or -1
+	children []*pathNode // the keys that follow the key in the paths
+	paths    []int       // indexes of the paths that end at the key
+	seen     bool        // the key matched an Object key
+}
+
+// add returns the child node for a path key, which is added if needed.
+func (node *pathNode) add(key string, index int) *pathNode {
+	for _, child := range node.children {
+		if child.key == key && child.index == index {
+			return child
+		}
+	}
+	child := &pathNode{key: key, index: index}
+	node.children = append(node.children, child)
+	return child
+}
+
+// keynode returns the child node for an Object key token, or nil if there
+// is none. A key that already matched a value does not match again, such
+// that the first of duplicate keys is used, like Get.
+func keynode[T Input](node *pathNode, tok T, info int) *pathNode {
+	var found *pathNode
+	for _, child := range node.children {
+		if !child.seen && keyeq(tok, info, child.key) {
+			child.seen = true
+			found = found.merge(child)
+		}
+	}
+	return found
+}
+
+// elem returns the child node for an Array index, or nil if there is none.
+func (node *pathNode) elem(index int) *pathNode {
+	var found *pathNode
+	for _, child := range node.children {
+		if child.index == index {
+			found = found.merge(child)
+		}
+	}
+	return found
+}
+
+// merge returns a node with the children and paths of both nodes, for the
+// nodes of different path keys that match the same value, such as "1" and
+// "\1" for an Object. It returns 'other' when 'node' is nil.
+func (node *pathNode) merge(other *pathNode) *pathNode {
+	if node == nil {
+		return other
+	}
+	merged := &pathNode{key: node.key, index: node.index}
+	merged.children = append(append(merged.children, node.children...),
+		other.children...)
+	merged.paths = append(append(merged.paths, node.paths...),
+		other.paths...)
+	return merged
+}
+
+// getFrame is an open container for GetMany.
+type getFrame struct {
+	node  *pathNode // node for the container, or nil if it's skipped
+	start int       // start index of the container
+	info  int       // info of the container, without the Open bit
+	n     int       // index of the next element, in an Array
+}
+
+// pathkey returns the first key of a path, without its '\' escapes, along
+// with its Array index, and the rest of the path. It returns false when the
+// key is the last key.
+func pathkey(path string) (key, rest string, index int, more bool) {
+	key = path
+	esc := false
+	for i := 0; i < len(path); i++ {
+		if path[i] == '\\' {
+			esc = true
+			i++
+		} else if path[i] == '.' {
+			key, rest, more = path[:i], path[i+1:], true
+			break
+		}
+	}
+	if esc {
+		return pathunescape(key), rest, -1, more
+	}
+	return key, rest, pathindex(key), more
+}
+
+// pathindex returns the Array index for a path key, or -1 if the key is not
+// an index.
+func pathindex(key string) int {
+	if len(key) == 0 || len(key) > 9 || (key[0] == '0' && len(key) > 1) {
+		return -1
+	}
+	var index int
+	for i := 0; i < len(key); i++ {
+		if !isnum(key[i]) {
+			return -1
+		}
+		index = index*10 + int(key[i]-'0')
+	}
+	return index
+}
+
+// keyeq returns true if the key token, with its quotes, is equal to the
+// unescaped path key.
+func keyeq[T Input](tok T, info int, key string) bool {
+	if info&Escaped == 0 {
+		return string(tok[1:len(tok)-1]) == key
+	}
+	str, err := Unescape(tok)
+	return err == nil && str == key
+}
+
+// pathunescape returns the key without its '\' escapes.
+func pathunescape(key string) string {
+	var buf []byte
+	for i := 0; i < len(key); i++ {
+		if key[i] == '\\' && i+1 < len(key) {
+			i++
+		}
+		buf = append(buf, key[i])
+	}
+	return string(buf)
+}
@@ -0,0 +1,149 @@
+package pjson
+
+import (
+	"fmt"
+	"testing"
+)
+
+var getJSON = `{
+  "name": {"first": "Tom", "last": "Anderson"},
+  "age":37,
+  "children": ["Sara","Alex","Jack"],
+  "fav.movie": "Deer Hunter",
+  "friends": [
+    {"first": "Dale", "last": "Murphy", "age": 44, "nets": ["ig", "fb", "tw"]},
+    {"first": "Roger", "last": "Craig", "age": 68, "nets": ["fb", "tw"]},
+    {"first": "Jane", "last": "Murphy", "age": 47, "nets": ["ig", "tw"]}
+  ],
+  "a\u002eb": {"c\"d": [[1], {"2": 3}]},
+  "": {"": "empty"}
+}`
+
+func TestGet(t *testing.T) {
+	test := func(path, expect string, expectInfo int) {
+		t.Helper()
+		start, end, info := Get(getJSON, path)
+		if getJSON[start:end] != expect || info != expectInfo {
+			t.Fatalf("%q: expected %s %d, got %s %d", path, expect,
+				expectInfo, getJSON[start:end], info)
+		}
+		start2, end2, info2 := Get([]byte(getJSON), path)
+		if start2 != start || end2 != end || info2 != info {
+			t.Fatalf("%q: []byte mismatch", path)
+		}
+	}
+	test("name.last", `"Anderson"`, Value|String)
+	test("age", `37`, Value|Number)
+	test("children", `["Sara","Alex","Jack"]`, Value|Array)
+	test("children.1", `"Alex"`, Value|String)
+	test("children.3", ``, 0)
+	test("children.x", ``, 0)
+	test("children.-1", ``, 0)
+	test("children.01", ``, 0)
+	test(`fav\.movie`, `"Deer Hunter"`, Value|String)
+	test("fav.movie", ``, 0)
+	test("friends.1.first", `"Roger"`, Value|String)
+	test("friends.2.nets.1", `"tw"`, Value|String)
+	test("friends.0", `{"first": "Dale", "last": "Murphy", "age": 44, `+
+		`"nets": ["ig", "fb", "tw"]}`, Value|Object)
+	test("friends.1.nets.2", ``, 0)
+	test(`a\.b.c"d.1.2`, `3`, Value|Number)
+	test(`a\.b.c"d.0`, `[1]`, Value|Array)
+	test(`a\.b.c"d.0.0.0`, ``, 0)
+	test("age.x", ``, 0)
+	test("x", ``, 0)
+	test("x.y", ``, 0)
+	test(".", `"empty"`, Value|String)
+	test("", getJSON, Object)
+
+	for _, tt := range []struct {
+		json, path, expect string
+	}{
+		{`1`, ``, `1`},
+		{` "a" `, ``, `"a"`},
+		{`1`, `a`, ``},
+		{`[1,[2,3]]`, `1.0`, `2`},
+		{`{"a":1,"a":2}`, `a`, `1`},
+		{`{"a":1,"b":[}`, `a`, `1`},
+		{`{"a":[1,}, "b":2}`, `b`, ``},
+		{`{"a":{"b":1}`, `a`, `{"b":1}`},
+		{`[1,2`, `1`, `2`},
+		{`{"é":1}`, `é`, `1`},
+		{`{"a\\b":1}`, `a\\b`, `1`},
+	} {
+		start, end, _ := Get(tt.json, tt.path)
+		if tt.json[start:end] != tt.expect {
+			t.Fatalf("%q %q: expected %s, got %s", tt.json, tt.path,
+				tt.expect, tt.json[start:end])
+		}
+	}
+}
+
+func TestGetAllocs(t *testing.T) {
+	json := []byte(getJSON)
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, _, info := Get(json, "friends.2.nets.1"); info == 0 {
+			panic("not found")
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected 0 allocs, got %v", allocs)
+	}
+}
+
+func TestGetMany(t *testing.T) {
+	paths := []string{
+		"name.last", "age", "children", "children.1", "children.3",
+		"children.x", "children.01", `fav\.movie`, "fav.movie",
+		"friends.1.first", "friends.2.nets.1", "friends.0", "friends.1.nets.2",
+		`a\.b.c"d.1.2`, `a\.b.c"d.0`, `a\.b.c"d.0.0.0`, "age.x", "x", "x.y",
+		".", "", "name", "name.first", "friends.1.first",
+	}
+	test := func(json string, paths []string) {
+		t.Helper()
+		results := GetMany(json, paths...)
+		if len(results) != len(paths) {
+			t.Fatalf("expected %d results, got %d", len(paths), len(results))
+		}
+		for i, path := range paths {
+			start, end, info := Get(json, path)
+			if results[i] != (Result{start, end, info}) {
+				t.Fatalf("%q %q: expected %v, got %v", json, path,
+					Result{start, end, info}, results[i])
+			}
+		}
+		if results2 := GetMany([]byte(json), paths...); fmt.Sprint(results2) !=
+			fmt.Sprint(results) {
+			t.Fatalf("%q: []byte mismatch", json)
+		}
+	}
+	test(getJSON, paths)
+	for i := range paths {
+		test(getJSON, paths[i:])
+		test(getJSON, paths[:i])
+	}
+	test(`{"a":1,"a":{"b":2},"c":[0,1]}`, []string{"a", "a.b", "c.1", "c.0"})
+	test(`{"a":{"b":2},"a":{"c":3}}`, []string{"a.b", "a.c"})
+	test(`[1,[2,3]]`, []string{"1.0", "0", "1", ""})
+	test(`1`, []string{"", "a"})
+	// An escaped index is a key, which is not an index for an Array.
+	test(`{"a":["x","y"],"b":{"1":"z"}}`, []string{`a.\1`, "a.1", `b.\1`,
+		"b.1"})
+	test(`{"a":["x","y"],"b":{"1":"z"}}`, []string{"a.1", `a.\1`, "b.1",
+		`b.\1`})
+	test(`{"a":{"1":{"0":1}},"b":[2,{"0":3}]}`, []string{`a.\1.0`, "a.1.0",
+		`a.1.\0`, "b.1.0", `b.\1.0`, `b.1.\0`})
+
+	// The parsing stops once all of the paths are found.
+	json := `{"a":1,"b":{"c":[2,3]},"d":`
+	results := GetMany(json, "b.c.1", "a", "b")
+	if fmt.Sprint(results) != fmt.Sprint([]Result{
+		{19, 20, Value | Number}, {5, 6, Value | Number},
+		{11, 22, Value | Object},
+	}) {
+		t.Fatalf("got %v", results)
+	}
+	if results := GetMany(json); len(results) != 0 {
+		t.Fatalf("expected no results, got %v", results)
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/tidwall/pjson
 
-go 1.15
+go 1.18
 
 require (
 	github.com/tidwall/lotsa v1.0.1
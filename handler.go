@@ -0,0 +1,99 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+// Handler receives the elements of a JSON document from ParseHandler, with
+// one method for each kind of element.
+// The 'start', 'end' and 'info' params, and the return values, work the same
+// as they do for the 'iter' function of Parse. Such that returning -1 from
+// ObjectStart or ArrayStart skips the children of the Object or Array, and
+// returning 0 from any method stops the parsing.
+type Handler interface {
+	ObjectStart(start, end, info int) int
+	ObjectEnd(start, end, info int) int
+	ArrayStart(start, end, info int) int
+	ArrayEnd(start, end, info int) int
+	Key(start, end, info int) int
+	String(start, end, info int) int
+	Number(start, end, info int) int
+	Bool(start, end, info int) int // 'info' has the True or False bit
+	Null(start, end, info int) int
+}
+
+// BaseHandler is a Handler that continues the parsing for all elements. It
+// can be embedded in a type that only needs some of the Handler methods.
+type BaseHandler struct{}
+
+// ObjectStart continues the parsing.
+func (BaseHandler) ObjectStart(start, end, info int) int { return 1 }
+
+// ObjectEnd continues the parsing.
+func (BaseHandler) ObjectEnd(start, end, info int) int { return 1 }
+
+// ArrayStart continues the parsing.
+func (BaseHandler) ArrayStart(start, end, info int) int { return 1 }
+
+// ArrayEnd continues the parsing.
+func (BaseHandler) ArrayEnd(start, end, info int) int { return 1 }
+
+// Key continues the parsing.
+func (BaseHandler) Key(start, end, info int) int { return 1 }
+
+// String continues the parsing.
+func (BaseHandler) String(start, end, info int) int { return 1 }
+
+// Number continues the parsing.
+func (BaseHandler) Number(start, end, info int) int { return 1 }
+
+// Bool continues the parsing.
+func (BaseHandler) Bool(start, end, info int) int { return 1 }
+
+// Null continues the parsing.
+func (BaseHandler) Null(start, end, info int) int { return 1 }
+
+// ParseHandler parses JSON like ParseErr, but the elements are passed to the
+// methods of a Handler rather than to an 'iter' function. The Comma, Colon
+// and Comment elements are not passed to the Handler.
+// Parsing strict JSON, with an 'opts' of zero, does not allocate memory.
+func ParseHandler[T Input](json T, opts int, h Handler) (int, error) {
+	if opts != 0 {
+		return ParseErr(json, opts, func(start, end, info int) int {
+			return handle(h, start, end, info)
+		})
+	}
+	// The iter function does not escape from vdoc, so it's not allocated.
+	i, ok, _ := vdoc(json, 0, func(start, end, info int) int {
+		return handle(h, start, end, info)
+	}, DefaultMaxDepth)
+	if ok {
+		return i, nil
+	}
+	return syntaxErr(json, Limits{})
+}
+
+// handle passes an element to the Handler method for the element.
+func handle(h Handler, start, end, info int) int {
+	switch {
+	case info&Key == Key:
+		return h.Key(start, end, info)
+	case info&String == String:
+		return h.String(start, end, info)
+	case info&Number == Number:
+		return h.Number(start, end, info)
+	case info&(True|False) != 0:
+		return h.Bool(start, end, info)
+	case info&Null == Null:
+		return h.Null(start, end, info)
+	case info&(Object|Open) == Object|Open:
+		return h.ObjectStart(start, end, info)
+	case info&(Object|Close) == Object|Close:
+		return h.ObjectEnd(start, end, info)
+	case info&(Array|Open) == Array|Open:
+		return h.ArrayStart(start, end, info)
+	case info&(Array|Close) == Array|Close:
+		return h.ArrayEnd(start, end, info)
+	}
+	return 1
+}
@@ -0,0 +1,112 @@
+package pjson
+
+import (
+	"fmt"
+	"testing"
+)
+
+type testHandler struct {
+	json []byte
+	out  []string
+	skip bool // skip the children of nested containers
+	stop string
+}
+
+func (h *testHandler) add(kind string, start, end, info int) int {
+	h.out = append(h.out, kind+":"+string(h.json[start:end]))
+	if kind == h.stop {
+		return 0
+	}
+	if h.skip && info&Value == Value && info&Open == Open {
+		return -1
+	}
+	return 1
+}
+
+func (h *testHandler) ObjectStart(start, end, info int) int {
+	return h.add("os", start, end, info)
+}
+func (h *testHandler) ObjectEnd(start, end, info int) int {
+	return h.add("oe", start, end, info)
+}
+func (h *testHandler) ArrayStart(start, end, info int) int {
+	return h.add("as", start, end, info)
+}
+func (h *testHandler) ArrayEnd(start, end, info int) int {
+	return h.add("ae", start, end, info)
+}
+func (h *testHandler) Key(start, end, info int) int {
+	return h.add("k", start, end, info)
+}
+func (h *testHandler) String(start, end, info int) int {
+	return h.add("s", start, end, info)
+}
+func (h *testHandler) Number(start, end, info int) int {
+	return h.add("n", start, end, info)
+}
+func (h *testHandler) Bool(start, end, info int) int {
+	return h.add(fmt.Sprint("b", info&True != 0), start, end, info)
+}
+func (h *testHandler) Null(start, end, info int) int {
+	return h.add("z", start, end, info)
+}
+
+type numberHandler struct {
+	BaseHandler
+	n int
+}
+
+func (h *numberHandler) Number(start, end, info int) int {
+	h.n++
+	return 1
+}
+
+func TestParseHandler(t *testing.T) {
+	json := []byte(`{"a":[1,"x",true,false,null],"b":{"c":{}},"d":2.5}`)
+	for _, opts := range []int{0, AllowComments} {
+		h := &testHandler{json: json}
+		n, err := ParseHandler(json, opts, h)
+		expect := `[os:{ k:"a" as:[ n:1 s:"x" btrue:true bfalse:false ` +
+			`z:null ae:] k:"b" os:{ k:"c" os:{ oe:} oe:} k:"d" n:2.5 oe:}]`
+		if n != len(json) || err != nil || fmt.Sprint(h.out) != expect {
+			t.Fatalf("expected %s, got %v %d %v", expect, h.out, n, err)
+		}
+
+		h = &testHandler{json: json, skip: true}
+		ParseHandler(json, opts, h)
+		expect = `[os:{ k:"a" as:[ ae:] k:"b" os:{ oe:} k:"d" n:2.5 oe:}]`
+		if fmt.Sprint(h.out) != expect {
+			t.Fatalf("expected %s, got %v", expect, h.out)
+		}
+
+		h = &testHandler{json: json, stop: "s"}
+		n, err = ParseHandler(json, opts, h)
+		if n != 11 || err != nil {
+			t.Fatalf("expected 11, got %d %v", n, err)
+		}
+
+		nh := &numberHandler{}
+		ParseHandler(json, opts, nh)
+		if nh.n != 2 {
+			t.Fatalf("expected 2, got %d", nh.n)
+		}
+
+		n, err = ParseHandler([]byte(`[1,2,}`), opts, nh)
+		if n != 5 || err == nil {
+			t.Fatalf("expected an error at 5, got %d %v", n, err)
+		}
+	}
+}
+
+func TestParseHandlerAllocs(t *testing.T) {
+	json := []byte(`{"a":[1,"x",true,false,null],"b":{"c":{}},"d":2.5}`)
+	h := &numberHandler{}
+	allocs := testing.AllocsPerRun(100, func() {
+		if _, err := ParseHandler(json, 0, h); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations, got %.0f", allocs)
+	}
+}
@@ -0,0 +1,103 @@
+//go:build go1.23
+
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"io"
+	"iter"
+)
+
+// Tokens returns an iterator over the elements of a JSON document, which
+// works like Parse and ParseTokens. Each iteration uses a new Tokenizer for
+// the document, without options. Use the All iterator of a Tokenizer to skip
+// Objects and Arrays.
+//
+//	for tok, err := range pjson.Tokens(json) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Printf("%s\n", json[tok.Start:tok.End])
+//	}
+//
+// Breaking out of the loop stops the parsing, like returning 0 from the
+// 'iter' function of Parse. When the document is invalid, the iteration ends
+// with a zero Token and the *SyntaxError.
+func Tokens[T Input](json T) iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		NewTokenizer(json, 0).All()(yield)
+	}
+}
+
+// All returns an iterator over the remaining elements of the Tokenizer,
+// using Next. Calling Skip in the loop for an Open element skips the rest of
+// its Object or Array, like returning -1 from the 'iter' function of Parse,
+// except that the Close element is also skipped.
+//
+//	t := pjson.NewTokenizer(json, 0)
+//	for tok, err := range t.All() {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    if tok.Info&pjson.Open == pjson.Open && tok.Depth > 0 {
+//	        t.Skip()
+//	    }
+//	}
+//
+// The iteration ends at the end of the document, or with a zero Token and the
+// error that Next returns when the document is invalid.
+func (t *Tokenizer[T]) All() iter.Seq2[Token, error] {
+	return func(yield func(Token, error) bool) {
+		for {
+			tok, err := t.Next()
+			if err == io.EOF {
+				return
+			}
+			if !yield(tok, err) || err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ArrayElements returns an iterator over the elements of a JSON Array, where
+// each element is the complete element data, such as the bytes of a String
+// with its quotes or an entire Object.
+//
+//	for elem := range pjson.ArrayElements(json) {
+//	    fmt.Printf("%s\n", elem)
+//	}
+//
+// Nothing is returned when the document is not an Array. The children of the
+// Objects and Arrays elements are skipped, like returning -1 from the 'iter'
+// function of Parse for their Open elements, but they are still validated.
+// The iteration stops at an error, so the document should be checked with
+// ParseErr first, unless it's known to be valid.
+func ArrayElements[T Input](json T) iter.Seq[T] {
+	return func(yield func(T) bool) {
+		var mark int
+		Parse(json, 0, func(start, end, info int) int {
+			switch {
+			case info&Start == Start:
+				if info&(Array|Open) != Array|Open {
+					return 0
+				}
+			case info&(Value|Open) == Value|Open:
+				mark = start
+				return -1
+			case info&(Value|Close) == Value|Close:
+				if !yield(json[mark:end]) {
+					return 0
+				}
+			case info&Value == Value:
+				if !yield(json[start:end]) {
+					return 0
+				}
+			}
+			return 1
+		})
+	}
+}
@@ -0,0 +1,157 @@
+//go:build go1.23
+
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	json := []byte(`{"a":[1,{"b":2}],"c":{"d":[]}}`)
+	var out1 []string
+	ParseTokens(json, 0, func(tok Token) int {
+		out1 = append(out1, fmt.Sprint(tok))
+		return 1
+	})
+	var out2 []string
+	for tok, err := range Tokens(json) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		out2 = append(out2, fmt.Sprint(tok))
+	}
+	if fmt.Sprint(out1) != fmt.Sprint(out2) {
+		t.Fatalf("expected %v, got %v", out1, out2)
+	}
+
+	// Break stops the parsing.
+	var n int
+	for tok := range Tokens(json) {
+		n++
+		if tok.Depth == 2 {
+			break
+		}
+	}
+	if n != 5 {
+		t.Fatalf("expected 5, got %d", n)
+	}
+
+	// The error is last.
+	json = []byte(`[1,2,x]`)
+	var toks []string
+	var err error
+	for tok, err2 := range Tokens(json) {
+		if err2 != nil {
+			err = err2
+			break
+		}
+		toks = append(toks, string(json[tok.Start:tok.End]))
+	}
+	var serr *SyntaxError
+	if fmt.Sprint(toks) != `[[ 1 , 2 ,]` || !errors.As(err, &serr) ||
+		serr.Offset != 5 {
+		t.Fatalf("got %v %v", toks, err)
+	}
+}
+
+func TestTokenizerAll(t *testing.T) {
+	// Skip works like returning -1 from iter, without the Close element.
+	json := []byte(`{"a":[1,{"b":2}],"c":{"d":[]},"e":3}`)
+	var out1 []string
+	ParseTokens(json, 0, func(tok Token) int {
+		if tok.Depth == 1 && tok.Info&Close == Close {
+			return 1
+		}
+		out1 = append(out1, fmt.Sprint(tok))
+		if tok.Depth == 1 && tok.Info&Open == Open {
+			return -1
+		}
+		return 1
+	})
+	var out2 []string
+	tz := NewTokenizer(json, 0)
+	for tok, err := range tz.All() {
+		if err != nil {
+			t.Fatal(err)
+		}
+		out2 = append(out2, fmt.Sprint(tok))
+		if tok.Depth == 1 && tok.Info&Open == Open {
+			if err := tz.Skip(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if fmt.Sprint(out1) != fmt.Sprint(out2) {
+		t.Fatalf("expected %v, got %v", out1, out2)
+	}
+
+	// A skipped Object or Array is still validated.
+	tz = NewTokenizer([]byte(`[1,[2,x],3]`), 0)
+	var errs []error
+	for tok, err := range tz.All() {
+		if err != nil {
+			errs = append(errs, err)
+		} else if tok.Depth == 1 && tok.Info&Open == Open {
+			tz.Skip()
+		}
+	}
+	var serr *SyntaxError
+	if len(errs) != 1 || !errors.As(errs[0], &serr) || serr.Offset != 6 {
+		t.Fatalf("got %v", errs)
+	}
+
+	// Tokens starts over for each loop.
+	seq := Tokens(`[1,2]`)
+	for range seq {
+		break
+	}
+	var n int
+	for range seq {
+		n++
+	}
+	if n != 5 {
+		t.Fatalf("expected 5, got %d", n)
+	}
+}
+
+func TestArrayElements(t *testing.T) {
+	test := func(json, expect string) {
+		t.Helper()
+		var out []string
+		for elem := range ArrayElements([]byte(json)) {
+			out = append(out, string(elem))
+		}
+		if fmt.Sprint(out) != expect {
+			t.Fatalf("%q: expected %s, got %v", json, expect, out)
+		}
+	}
+	test(`[1, "a", {"b":[2,3]}, [[4],5], true, null, []]`,
+		`[1 "a" {"b":[2,3]} [[4],5] true null []]`)
+	test(`[]`, `[]`)
+	test(`{"a":1}`, `[]`)
+	test(`1`, `[]`)
+	test(`[1,{"a":x},2]`, `[1]`)
+
+	// Break stops the parsing.
+	var out []string
+	for elem := range ArrayElements([]byte(`[1,[2],3,4]`)) {
+		out = append(out, string(elem))
+		if len(out) == 2 {
+			break
+		}
+	}
+	if fmt.Sprint(out) != `[1 [2]]` {
+		t.Fatalf("got %v", out)
+	}
+
+	// The elements of a string document are strings.
+	out = nil
+	for elem := range ArrayElements(`["a",{"b":1}]`) {
+		out = append(out, elem)
+	}
+	if fmt.Sprint(out) != `["a" {"b":1}]` {
+		t.Fatalf("got %v", out)
+	}
+}
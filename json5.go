@@ -0,0 +1,226 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+// JSON5 scanning functions, which are used by the stream when the AllowJSON5
+// option is provided. See https://spec.json5.org
+
+// ws5 returns the length of the JSON5 whitespace character at json[i], or zero
+// if there is none. It returns -1 if json[i:] is an incomplete prefix of a
+// whitespace character.
+func ws5[T Input](json T, i int) int {
+	switch json[i] {
+	case ' ', '\t', '\n', '\r', '\v', '\f':
+		return 1
+	case 0xC2: // U+00A0 no-break space
+		return mbws(json, i, "\xC2\xA0")
+	case 0xEF: // U+FEFF byte order mark
+		return mbws(json, i, "\xEF\xBB\xBF")
+	case 0xE2: // U+2028 line separator, U+2029 paragraph separator
+		if n := mbws(json, i, "\xE2\x80\xA8"); n != 0 {
+			return n
+		}
+		return mbws(json, i, "\xE2\x80\xA9")
+	}
+	return 0
+}
+
+// mbws returns the length of the multibyte whitespace character 'ch' at
+// json[i], or zero if it's not there, or -1 if json[i:] is an incomplete prefix
+// of the character.
+func mbws[T Input](json T, i int, ch string) int {
+	rest := json[i:]
+	if len(rest) < len(ch) {
+		if string(rest) == ch[:len(rest)] {
+			return -1
+		}
+		return 0
+	}
+	if string(rest[:len(ch)]) == ch {
+		return len(ch)
+	}
+	return 0
+}
+
+var identtoks = [256]byte{
+	'$': 1, '_': 1,
+	'A': 1, 'B': 1, 'C': 1, 'D': 1, 'E': 1, 'F': 1, 'G': 1, 'H': 1, 'I': 1,
+	'J': 1, 'K': 1, 'L': 1, 'M': 1, 'N': 1, 'O': 1, 'P': 1, 'Q': 1, 'R': 1,
+	'S': 1, 'T': 1, 'U': 1, 'V': 1, 'W': 1, 'X': 1, 'Y': 1, 'Z': 1,
+	'a': 1, 'b': 1, 'c': 1, 'd': 1, 'e': 1, 'f': 1, 'g': 1, 'h': 1, 'i': 1,
+	'j': 1, 'k': 1, 'l': 1, 'm': 1, 'n': 1, 'o': 1, 'p': 1, 'q': 1, 'r': 1,
+	's': 1, 't': 1, 'u': 1, 'v': 1, 'w': 1, 'x': 1, 'y': 1, 'z': 1,
+	'0': 2, '1': 2, '2': 2, '3': 2, '4': 2, '5': 2, '6': 2, '7': 2, '8': 2,
+	'9': 2,
+}
+
+// isidentstart returns true if the byte can start an unquoted key. All
+// non-ASCII bytes are allowed, which are assumed to be part of a Unicode
+// letter.
+func isidentstart(ch byte) bool {
+	return identtoks[ch] == 1 || ch >= 0x80
+}
+
+// videntifier - the first character has already been checked with
+// isidentstart, but not processed, because it may need UTF-8 validation.
+func videntifier[T Input](json T, i int, utf8 bool) (outi, info int, ok bool) {
+	for i < len(json) {
+		if json[i] < 0x80 {
+			if identtoks[json[i]] == 0 {
+				break
+			}
+		} else if utf8 {
+			info |= NonASCII
+			if i, ok = vutf8(json, i); !ok {
+				return i, info, false
+			}
+			continue
+		}
+		i++
+	}
+	return i, info, true
+}
+
+func ishex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') ||
+		(ch >= 'a' && ch <= 'f') ||
+		(ch >= 'A' && ch <= 'F')
+}
+
+// vstring5 - the prefix quote character has already been processed
+func vstring5[T Input](json T, i int, quote byte, utf8 bool) (outi, info int,
+	ok bool,
+) {
+	for ; i < len(json); i++ {
+		if json[i] >= 0x80 && utf8 {
+			info |= NonASCII
+			end, ok := vutf8(json, i)
+			if !ok {
+				return end, info, false
+			}
+			i = end - 1
+			continue
+		}
+		switch json[i] {
+		case quote:
+			return i + 1, info, true
+		case '\n', '\r':
+			return i, info, false
+		case '\\':
+			info |= Escaped
+			i++
+			if i == len(json) {
+				return i, info, false
+			}
+			switch json[i] {
+			case '1', '2', '3', '4', '5', '6', '7', '8', '9':
+				return i, info, false
+			case '0':
+				if i+1 < len(json) && isnum(json[i+1]) {
+					return i + 1, info, false
+				}
+			case 'x', 'u':
+				n := 2
+				if json[i] == 'u' {
+					n = 4
+				}
+				for j := 0; j < n; j++ {
+					i++
+					if i >= len(json) {
+						return i, info, false
+					}
+					if !ishex(json[i]) {
+						return i, info, false
+					}
+				}
+				if n == 4 && json[i-3]|0x20 == 'd' {
+					var paired bool
+					if i, paired = vsurrogate(json, i); !paired {
+						info |= LoneSurrogate
+					}
+				}
+			case '\r':
+				// line continuation, which may be "\r\n"
+				if i+1 < len(json) && json[i+1] == '\n' {
+					i++
+				}
+			}
+		}
+	}
+	return i, info, false
+}
+
+// vnumber5 - nothing has been processed
+func vnumber5[T Input](json T, i int) (outi, info int, ok bool) {
+	if json[i] == '-' {
+		info |= Sign
+		i++
+	} else if json[i] == '+' {
+		i++
+	}
+	if i == len(json) {
+		return i, info, false
+	}
+	switch json[i] {
+	case 'I':
+		if j := litfail(json, i+1, "nfinity"); j != i+8 {
+			return j, info, false
+		}
+		return i + 8, info | Infinity, true
+	case 'N':
+		if j := litfail(json, i+1, "aN"); j != i+3 {
+			return j, info, false
+		}
+		return i + 3, info | NaN, true
+	case '0':
+		if i+1 < len(json) && (json[i+1] == 'x' || json[i+1] == 'X') {
+			info |= Hex
+			i += 2
+			if i == len(json) || !ishex(json[i]) {
+				return i, info, false
+			}
+			for ; i < len(json); i++ {
+				if !ishex(json[i]) {
+					break
+				}
+			}
+			return i, info, true
+		}
+	}
+	var digits bool
+	if json[i] == '0' {
+		digits = true
+		i++
+	} else {
+		for ; i < len(json) && isnum(json[i]); i++ {
+			digits = true
+		}
+	}
+	if i < len(json) && json[i] == '.' {
+		// The integer part or the fraction part may be omitted, but not
+		// both.
+		info |= Dot
+		i++
+		for ; i < len(json) && isnum(json[i]); i++ {
+			digits = true
+		}
+	}
+	if !digits {
+		return i, info, false
+	}
+	if i < len(json) && (json[i] == 'e' || json[i] == 'E') {
+		info |= E
+		i++
+		if i < len(json) && (json[i] == '+' || json[i] == '-') {
+			i++
+		}
+		if i == len(json) || !isnum(json[i]) {
+			return i, info, false
+		}
+		for ; i < len(json) && isnum(json[i]); i++ {
+		}
+	}
+	return i, info, true
+}
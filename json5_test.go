@@ -0,0 +1,126 @@
+package pjson
+
+import (
+	"fmt"
+	"testing"
+)
+
+var json5Doc = `{
+	unquoted: 'and you can quote me on that',
+	singleQuotes: 'I can use "double quotes" here',
+	lineBreaks: "Look, Mom! \
+No \\n's!",
+	hexadecimal: 0xdecaf,
+	leadingDecimalPoint: .8675309, andTrailing: 8675309.,
+	positiveSign: +1,
+	trailingComma: 'in objects', andIn: ['arrays',],
+	"backwardsCompatible": "with JSON",
+	$_ident9: [Infinity, -Infinity, +NaN, -0x1F, 1e+3, .5e-2, '\x41\0'],
+	escapes: '\'\vé\
+',` + " \u00a0\ufeff\u2028\v\f" + `
+}`
+
+func testjson5(t *testing.T, json string, expect bool) {
+	t.Helper()
+	n, err := ParseErr([]byte(json), AllowJSON5, nil)
+	if (err == nil) != expect {
+		t.Fatalf("%q: expected %t, got %d %v", json, expect, n, err)
+	}
+	if expect && Parse([]byte(json), 0, nil) > 0 && json != "0" &&
+		json != `""` {
+		t.Fatalf("%q: expected invalid strict json", json)
+	}
+}
+
+func TestJSON5(t *testing.T) {
+	testjson5(t, json5Doc, true)
+	testjson5(t, `{a:1}`, true)
+	testjson5(t, `{a:1,}`, true)
+	testjson5(t, `{a:1,,}`, false)
+	testjson5(t, `{,}`, false)
+	testjson5(t, `[,]`, false)
+	testjson5(t, `[1,]`, true)
+	testjson5(t, `[1,,]`, false)
+	testjson5(t, `{1a:1}`, false)
+	testjson5(t, `{'a':'b'}`, true)
+	testjson5(t, `{'a":'b'}`, false)
+	testjson5(t, `'\1'`, false)
+	testjson5(t, `'\01'`, false)
+	testjson5(t, `'\0'`, true)
+	testjson5(t, `'\x4'`, false)
+	testjson5(t, `'\x4g'`, false)
+	testjson5(t, "'a\nb'", false)
+	testjson5(t, "'a\\\r\nb'", true)
+	testjson5(t, "'a\tb'", true)
+	testjson5(t, `.`, false)
+	testjson5(t, `+`, false)
+	testjson5(t, `-.e1`, false)
+	testjson5(t, `5.e1`, true)
+	testjson5(t, `0x`, false)
+	testjson5(t, `0xG`, false)
+	testjson5(t, `0X0aF`, true)
+	testjson5(t, `Infinit`, false)
+	testjson5(t, `Nan`, false)
+	testjson5(t, `-NaN`, true)
+	testjson5(t, `01`, false)
+	testjson5(t, `0`, true)
+	testjson5(t, `""`, true)
+	testjson5(t, `undefined`, false)
+	testjson5(t, "\xc2", false)
+	testjson5(t, "\xc2\xa1", false)
+	testjson5(t, "1\xe2\x80", false)
+}
+
+func TestJSON5Info(t *testing.T) {
+	json := []byte(`{a:'b',"c":[0x1F,-Infinity,NaN,+.5,1.,'\x41'],}`)
+	var out []string
+	Parse(json, AllowJSON5, func(start, end, info int) int {
+		if info&(Key|String|Number) != 0 {
+			out = append(out, fmt.Sprintf("%s:%d", json[start:end], info))
+		}
+		return 1
+	})
+	expect := []string{
+		fmt.Sprintf("a:%d", Key|Ident),
+		fmt.Sprintf("'b':%d", Value|String|SingleQuote),
+		fmt.Sprintf(`"c":%d`, Key|String),
+		fmt.Sprintf("0x1F:%d", Value|Number|Hex),
+		fmt.Sprintf("-Infinity:%d", Value|Number|Infinity|Sign),
+		fmt.Sprintf("NaN:%d", Value|Number|NaN),
+		fmt.Sprintf("+.5:%d", Value|Number|Dot),
+		fmt.Sprintf("1.:%d", Value|Number|Dot),
+		fmt.Sprintf(`'\x41':%d`, Value|String|SingleQuote|Escaped),
+	}
+	if fmt.Sprint(out) != fmt.Sprint(expect) {
+		t.Fatalf("expected %v, got %v", expect, out)
+	}
+}
+
+func TestJSON5Parser(t *testing.T) {
+	json := []byte(json5Doc)
+	var evs1 []streamEvent
+	n1 := Parse(json, AllowJSON5, func(start, end, info int) int {
+		evs1 = append(evs1, streamEvent{start, end, info,
+			string(json[start:end])})
+		return 1
+	})
+	var evs2 []streamEvent
+	p := NewParser(AllowJSON5, func(start, end, info int, token []byte) int {
+		evs2 = append(evs2, streamEvent{start, end, info, string(token)})
+		return 1
+	})
+	for i := 0; i < len(json); i++ {
+		if _, err := p.Write(json[i : i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if n1 != len(json) || p.Offset() != n1 {
+		t.Fatalf("expected %d, got %d and %d", len(json), n1, p.Offset())
+	}
+	if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+		t.Fatalf("expected %v, got %v", evs1, evs2)
+	}
+}
@@ -0,0 +1,1181 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// QueryError is returned by Query when the JSONPath expression is invalid.
+type QueryError struct {
+	Offset int    // offset of the error in the expression
+	Msg    string // description of the error
+}
+
+func (e *QueryError) Error() string {
+	return "pjson: invalid query at offset " + strconv.Itoa(e.Offset) + ": " +
+		e.Msg
+}
+
+// Query returns the values in a JSON document that are selected by a
+// JSONPath expression (RFC 9535), such as "$.store.book[?@.price < 10].title",
+// "$..author" or "$.items[1:5]". Each Result is a value in the document,
+// where json[Start:End] is the complete value and Info is the info of the
+// value, like Get.
+// The results are in the order of the nodelist from the RFC, which may
+// include the same value more than once.
+// Queries that only have names, wildcards and non-negative indexes, with one
+// selector in each segment and at most one descendant segment (".."), are
+// evaluated in one pass over the document. Other queries, such as queries
+// with filters, slices or negative indexes, scan the Objects and Arrays on
+// the query path once for each segment. Either way, the Objects and Arrays
+// that cannot have results are skipped, like returning -1 from the 'iter'
+// function of Parse.
+// It returns a *QueryError when the expression is invalid, or a
+// *SyntaxError when the document is invalid.
+func Query[T Input](json T, path string) ([]Result, error) {
+	segs, err := compileQuery(path)
+	if err != nil {
+		return nil, err
+	}
+	q := &qeval[T]{json: json}
+	if streamable(segs) {
+		return q.stream(segs)
+	}
+	// Find the root value, which also validates the document.
+	var mark int
+	_, err = ParseErr(json, 0, func(start, end, info int) int {
+		switch {
+		case info&Open == Open:
+			mark = start
+			return -1
+		case info&Close == Close:
+			q.root = Result{mark, end, info &^ (End | Close)}
+		default:
+			q.root = Result{start, end, info &^ (Start | End)}
+		}
+		return 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	return q.run(segs, []Result{q.root}), nil
+}
+
+// Kinds of selectors
+const (
+	qName     = iota // member name, such as 'a' or .a
+	qWildcard        // all members or elements, * or .*
+	qIndex           // element index, such as 1 or -1
+	qSlice           // array slice, such as 1:5:2
+	qFilter          // filter expression, such as ?@.a
+)
+
+// qselector is a selector of a JSONPath segment.
+type qselector struct {
+	kind     int
+	name     string // member name, for qName
+	index    int    // element index for qIndex, or the start for qSlice
+	end      int    // end, for qSlice
+	step     int    // step, for qSlice
+	hasStart bool   // the start is provided, for qSlice
+	hasEnd   bool   // the end is provided, for qSlice
+	filter   *qexpr // filter expression, for qFilter
+}
+
+// qsegment is a segment of a JSONPath query, which selects the children of
+// a value, or of a value and its descendants when 'desc' is true.
+type qsegment struct {
+	desc bool
+	sels []qselector
+}
+
+// Kinds of filter expressions
+const (
+	qOr   = iota // a || b
+	qAnd         // a && b
+	qNot         // !a
+	qCmp         // comparison of the l and r operands
+	qTest        // test of the l operand, which is a query or a function
+)
+
+// qexpr is a filter expression.
+type qexpr struct {
+	op   int
+	a, b *qexpr   // operands, for qOr, qAnd and qNot
+	cmp  string   // comparison operator, for qCmp
+	l, r qoperand // operands, for qCmp and qTest
+}
+
+// Kinds of operands
+const (
+	qLiteral = iota // literal value
+	qQuery          // relative or absolute query
+	qFunc           // function
+)
+
+// qoperand is an operand of a filter expression or function.
+type qoperand struct {
+	kind int
+	lit  qvalue         // value, for qLiteral
+	rel  bool           // the query is relative to '@', for qQuery
+	segs []qsegment     // segments, for qQuery
+	name string         // function name, for qFunc
+	args []qoperand     // arguments, for qFunc
+	re   *regexp.Regexp // compiled literal pattern, for match and search
+	bad  bool           // the literal pattern is invalid
+}
+
+// Kinds of values
+const (
+	qNothing = iota // no value, such as from a query without results
+	qNull
+	qBool
+	qNum
+	qStr
+	qNode // Object or Array in the document
+)
+
+// qvalue is a value of a filter operand.
+type qvalue struct {
+	kind int
+	b    bool
+	num  float64
+	str  string
+	node Result
+}
+
+// maxInt53 is the largest integer in the I-JSON range.
+const maxInt53 = 1<<53 - 1
+
+// qparser parses a JSONPath expression.
+type qparser struct {
+	path string
+	i    int
+}
+
+// compileQuery returns the segments of a JSONPath expression.
+func compileQuery(path string) ([]qsegment, error) {
+	p := &qparser{path: path}
+	if !utf8.ValidString(path) {
+		return nil, p.fail("invalid UTF-8")
+	}
+	if !p.peek("$") {
+		return nil, p.fail("expected '$'")
+	}
+	p.i++
+	segs, err := p.segments()
+	if err != nil {
+		return nil, err
+	}
+	if p.i < len(path) {
+		return nil, p.fail("unexpected character")
+	}
+	return segs, nil
+}
+
+func (p *qparser) fail(msg string) error {
+	return &QueryError{Offset: p.i, Msg: msg}
+}
+
+func (p *qparser) peek(s string) bool {
+	return strings.HasPrefix(p.path[p.i:], s)
+}
+
+func (p *qparser) ws() {
+	for p.i < len(p.path) && (p.path[p.i] == ' ' || p.path[p.i] == '\t' ||
+		p.path[p.i] == '\n' || p.path[p.i] == '\r') {
+		p.i++
+	}
+}
+
+// segments parses the segments that follow a '$' or '@'.
+func (p *qparser) segments() ([]qsegment, error) {
+	var segs []qsegment
+	for {
+		mark := p.i
+		p.ws()
+		if !p.peek(".") && !p.peek("[") {
+			p.i = mark
+			return segs, nil
+		}
+		var seg qsegment
+		var err error
+		if p.peek("..") {
+			seg.desc = true
+			p.i += 2
+			if p.peek("[") {
+				seg.sels, err = p.bracket()
+			} else {
+				seg.sels, err = p.shorthand()
+			}
+		} else if p.peek(".") {
+			p.i++
+			seg.sels, err = p.shorthand()
+		} else {
+			seg.sels, err = p.bracket()
+		}
+		if err != nil {
+			return nil, err
+		}
+		segs = append(segs, seg)
+	}
+}
+
+// shorthand parses a wildcard or a member name that follows a dot.
+func (p *qparser) shorthand() ([]qselector, error) {
+	if p.peek("*") {
+		p.i++
+		return []qselector{{kind: qWildcard}}, nil
+	}
+	start := p.i
+	for ; p.i < len(p.path); p.i++ {
+		c := p.path[p.i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' ||
+			c >= 0x80 || (isnum(c) && p.i > start)) {
+			break
+		}
+	}
+	if p.i == start {
+		return nil, p.fail("expected a member name")
+	}
+	return []qselector{{kind: qName, name: p.path[start:p.i]}}, nil
+}
+
+// bracket parses the selectors in brackets.
+func (p *qparser) bracket() ([]qselector, error) {
+	p.i++
+	var sels []qselector
+	for {
+		p.ws()
+		sel, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		sels = append(sels, sel)
+		p.ws()
+		if p.peek(",") {
+			p.i++
+			continue
+		}
+		if p.peek("]") {
+			p.i++
+			return sels, nil
+		}
+		return nil, p.fail("expected ',' or ']'")
+	}
+}
+
+func (p *qparser) selector() (qselector, error) {
+	var sel qselector
+	if p.i == len(p.path) {
+		return sel, p.fail("expected a selector")
+	}
+	switch c := p.path[p.i]; c {
+	case '\'', '"':
+		name, err := p.str()
+		return qselector{kind: qName, name: name}, err
+	case '*':
+		p.i++
+		return qselector{kind: qWildcard}, nil
+	case '?':
+		p.i++
+		p.ws()
+		filter, err := p.or()
+		return qselector{kind: qFilter, filter: filter}, err
+	}
+	index, ok, err := p.int()
+	if err != nil {
+		return sel, err
+	}
+	mark := p.i
+	p.ws()
+	if !p.peek(":") {
+		if !ok {
+			return sel, p.fail("expected a selector")
+		}
+		p.i = mark
+		return qselector{kind: qIndex, index: index}, nil
+	}
+	sel = qselector{kind: qSlice, index: index, hasStart: ok, step: 1}
+	p.i++
+	p.ws()
+	if sel.end, sel.hasEnd, err = p.int(); err != nil {
+		return sel, err
+	}
+	p.ws()
+	if p.peek(":") {
+		p.i++
+		p.ws()
+		step, ok, err := p.int()
+		if err != nil {
+			return sel, err
+		}
+		if ok {
+			sel.step = step
+		}
+	}
+	return sel, nil
+}
+
+// int parses an integer, or returns false if there is none.
+func (p *qparser) int() (n int, ok bool, err error) {
+	i := p.i
+	if p.peek("-") {
+		i++
+	}
+	if i == len(p.path) || !isnum(p.path[i]) {
+		if i > p.i {
+			return 0, false, p.fail("expected a digit")
+		}
+		return 0, false, nil
+	}
+	if p.path[i] == '0' && (i > p.i || i+1 < len(p.path) &&
+		isnum(p.path[i+1])) {
+		return 0, false, p.fail("invalid integer")
+	}
+	for i++; i < len(p.path) && isnum(p.path[i]); i++ {
+	}
+	x, err := strconv.ParseInt(p.path[p.i:i], 10, 64)
+	if err != nil || x > maxInt53 || x < -maxInt53 {
+		return 0, false, p.fail("integer out of range")
+	}
+	p.i = i
+	return int(x), true, nil
+}
+
+// str parses a string literal in single or double quotes.
+func (p *qparser) str() (string, error) {
+	quote := p.path[p.i]
+	p.i++
+	var buf []byte
+	for {
+		if p.i == len(p.path) {
+			return "", p.fail("unterminated string")
+		}
+		c := p.path[p.i]
+		switch {
+		case c == quote:
+			p.i++
+			return string(buf), nil
+		case c < 0x20:
+			return "", p.fail("invalid character in string")
+		case c != '\\':
+			buf = append(buf, c)
+			p.i++
+			continue
+		}
+		p.i++
+		if p.i == len(p.path) {
+			return "", p.fail("unterminated string")
+		}
+		switch c = p.path[p.i]; c {
+		case 'b':
+			buf = append(buf, '\b')
+		case 'f':
+			buf = append(buf, '\f')
+		case 'n':
+			buf = append(buf, '\n')
+		case 'r':
+			buf = append(buf, '\r')
+		case 't':
+			buf = append(buf, '\t')
+		case '/', '\\', quote:
+			buf = append(buf, c)
+		case 'u':
+			r, ok := unhex(p.path[p.i+1:], 4)
+			if !ok {
+				return "", p.fail("invalid escape")
+			}
+			if r >= 0xDC00 && r < 0xE000 {
+				return "", p.fail("invalid surrogate")
+			}
+			if r >= 0xD800 && r < 0xDC00 {
+				r2, ok := rune(0), false
+				if strings.HasPrefix(p.path[p.i+5:], "\\u") {
+					r2, ok = unhex(p.path[p.i+7:], 4)
+				}
+				if !ok || r2 < 0xDC00 || r2 >= 0xE000 {
+					return "", p.fail("invalid surrogate")
+				}
+				r = ((r - 0xD800) << 10) + (r2 - 0xDC00) + 0x10000
+				p.i += 6
+			}
+			buf = utf8.AppendRune(buf, r)
+			p.i += 4
+		default:
+			return "", p.fail("invalid escape")
+		}
+		p.i++
+	}
+}
+
+// or parses a logical expression.
+func (p *qparser) or() (*qexpr, error) {
+	a, err := p.and()
+	for err == nil {
+		mark := p.i
+		p.ws()
+		if !p.peek("||") {
+			p.i = mark
+			return a, nil
+		}
+		p.i += 2
+		p.ws()
+		var b *qexpr
+		b, err = p.and()
+		a = &qexpr{op: qOr, a: a, b: b}
+	}
+	return nil, err
+}
+
+func (p *qparser) and() (*qexpr, error) {
+	a, err := p.basic()
+	for err == nil {
+		mark := p.i
+		p.ws()
+		if !p.peek("&&") {
+			p.i = mark
+			return a, nil
+		}
+		p.i += 2
+		p.ws()
+		var b *qexpr
+		b, err = p.basic()
+		a = &qexpr{op: qAnd, a: a, b: b}
+	}
+	return nil, err
+}
+
+// basic parses a parenthesized expression, a comparison, or a test.
+func (p *qparser) basic() (*qexpr, error) {
+	not := p.peek("!")
+	if not {
+		p.i++
+		p.ws()
+	}
+	if p.peek("(") {
+		e, err := p.paren()
+		if err != nil || !not {
+			return e, err
+		}
+		return &qexpr{op: qNot, a: e}, nil
+	}
+	start := p.i
+	l, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	mark := p.i
+	p.ws()
+	var op string
+	for _, cmp := range []string{"==", "!=", "<=", ">=", "<", ">"} {
+		if p.peek(cmp) {
+			op = cmp
+			break
+		}
+	}
+	if op == "" || not {
+		// A test, which is a query or a function that returns a logical
+		// value.
+		p.i = mark
+		if l.kind == qLiteral || l.kind == qFunc && !logicalfunc(l.name) {
+			p.i = start
+			return nil, p.fail("expected a comparison")
+		}
+		e := &qexpr{op: qTest, l: l}
+		if not {
+			e = &qexpr{op: qNot, a: e}
+		}
+		return e, nil
+	}
+	if !qcomparable(&l) {
+		p.i = start
+		return nil, p.fail("operand is not comparable")
+	}
+	p.i += len(op)
+	p.ws()
+	start = p.i
+	r, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	if !qcomparable(&r) {
+		p.i = start
+		return nil, p.fail("operand is not comparable")
+	}
+	return &qexpr{op: qCmp, cmp: op, l: l, r: r}, nil
+}
+
+func (p *qparser) paren() (*qexpr, error) {
+	p.i++
+	p.ws()
+	e, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	p.ws()
+	if !p.peek(")") {
+		return nil, p.fail("expected ')'")
+	}
+	p.i++
+	return e, nil
+}
+
+// operand parses a literal, a query, or a function.
+func (p *qparser) operand() (qoperand, error) {
+	var op qoperand
+	if p.i == len(p.path) {
+		return op, p.fail("expected an operand")
+	}
+	switch c := p.path[p.i]; {
+	case c == '@' || c == '$':
+		p.i++
+		segs, err := p.segments()
+		return qoperand{kind: qQuery, rel: c == '@', segs: segs}, err
+	case c == '\'' || c == '"':
+		str, err := p.str()
+		return qoperand{lit: qvalue{kind: qStr, str: str}}, err
+	case c == '-' || isnum(c):
+		return p.number()
+	case c >= 'a' && c <= 'z':
+		start := p.i
+		for p.i < len(p.path) && (p.path[p.i] >= 'a' && p.path[p.i] <= 'z' ||
+			p.path[p.i] == '_' || isnum(p.path[p.i])) {
+			p.i++
+		}
+		name := p.path[start:p.i]
+		if p.peek("(") {
+			p.i = start
+			return p.function(name)
+		}
+		switch name {
+		case "true", "false":
+			return qoperand{lit: qvalue{kind: qBool, b: name == "true"}}, nil
+		case "null":
+			return qoperand{lit: qvalue{kind: qNull}}, nil
+		}
+		p.i = start
+	}
+	return op, p.fail("expected an operand")
+}
+
+// number parses a number literal.
+func (p *qparser) number() (qoperand, error) {
+	start := p.i
+	i := p.i
+	if p.path[i] == '-' {
+		i++
+	}
+	if i == len(p.path) || !isnum(p.path[i]) {
+		return qoperand{}, p.fail("invalid number")
+	}
+	if p.path[i] == '0' {
+		i++
+	} else {
+		for i < len(p.path) && isnum(p.path[i]) {
+			i++
+		}
+	}
+	digits := func() bool {
+		j := i
+		for i < len(p.path) && isnum(p.path[i]) {
+			i++
+		}
+		return i > j
+	}
+	if i < len(p.path) && p.path[i] == '.' {
+		i++
+		if !digits() {
+			p.i = i
+			return qoperand{}, p.fail("invalid number")
+		}
+	}
+	if i < len(p.path) && (p.path[i] == 'e' || p.path[i] == 'E') {
+		i++
+		if i < len(p.path) && (p.path[i] == '-' || p.path[i] == '+') {
+			i++
+		}
+		if !digits() {
+			p.i = i
+			return qoperand{}, p.fail("invalid number")
+		}
+	}
+	x, err := strconv.ParseFloat(p.path[start:i], 64)
+	if err != nil {
+		return qoperand{}, p.fail("number out of range")
+	}
+	p.i = i
+	return qoperand{lit: qvalue{kind: qNum, num: x}}, nil
+}
+
+// function parses a function and checks the types of its arguments.
+func (p *qparser) function(name string) (qoperand, error) {
+	start := p.i
+	op := qoperand{kind: qFunc, name: name}
+	p.i += len(name) + 1
+	p.ws()
+	for !p.peek(")") {
+		if len(op.args) > 0 {
+			if !p.peek(",") {
+				return op, p.fail("expected ',' or ')'")
+			}
+			p.i++
+			p.ws()
+		}
+		arg, err := p.operand()
+		if err != nil {
+			return op, err
+		}
+		op.args = append(op.args, arg)
+		p.ws()
+	}
+	p.i++
+	var ok bool
+	switch name {
+	case "length":
+		ok = len(op.args) == 1 && qcomparable(&op.args[0])
+	case "count", "value":
+		ok = len(op.args) == 1 && op.args[0].kind == qQuery
+	case "match", "search":
+		ok = len(op.args) == 2 && qcomparable(&op.args[0]) &&
+			qcomparable(&op.args[1])
+		if pat := &op.args[1]; ok && pat.kind == qLiteral &&
+			pat.lit.kind == qStr {
+			op.re, _ = iregexp(pat.lit.str, name == "match")
+			op.bad = op.re == nil
+		}
+	default:
+		p.i = start
+		return op, p.fail("unknown function")
+	}
+	if !ok {
+		p.i = start
+		return op, p.fail("invalid arguments")
+	}
+	return op, nil
+}
+
+// logicalfunc returns true for the functions that return a logical value,
+// rather than a value that can be compared.
+func logicalfunc(name string) bool {
+	return name == "match" || name == "search"
+}
+
+// qcomparable returns true if the operand is a literal, a singular query,
+// or a function that returns a value.
+func qcomparable(op *qoperand) bool {
+	switch op.kind {
+	case qQuery:
+		for _, seg := range op.segs {
+			if seg.desc || len(seg.sels) != 1 ||
+				(seg.sels[0].kind != qName && seg.sels[0].kind != qIndex) {
+				return false
+			}
+		}
+	case qFunc:
+		return !logicalfunc(op.name)
+	}
+	return true
+}
+
+// iregexp returns the regular expression for an I-Regexp (RFC 9485) pattern,
+// where a '.' outside of a character class does not match "\n" or "\r".
+// The 'full' param anchors the expression to match the entire string.
+func iregexp(pattern string, full bool) (*regexp.Regexp, error) {
+	var buf []byte
+	if full {
+		buf = append(buf, "^(?:"...)
+	}
+	class := false
+	for i := 0; i < len(pattern); i++ {
+		switch c := pattern[i]; {
+		case c == '\\' && i+1 < len(pattern):
+			buf = append(buf, c, pattern[i+1])
+			i++
+			continue
+		case c == '[':
+			class = true
+		case c == ']':
+			class = false
+		case c == '.' && !class:
+			buf = append(buf, `[^\n\r]`...)
+			continue
+		}
+		buf = append(buf, pattern[i])
+	}
+	if full {
+		buf = append(buf, ")$"...)
+	}
+	return regexp.Compile(string(buf))
+}
+
+// streamable returns true if a query can be evaluated in one pass, which is
+// when each segment has one name, wildcard or non-negative index, and at most
+// one segment is a descendant segment.
+func streamable(segs []qsegment) bool {
+	if len(segs) > 63 {
+		return false
+	}
+	ndesc := 0
+	for _, seg := range segs {
+		if seg.desc {
+			ndesc++
+		}
+		if len(seg.sels) != 1 || ndesc > 1 {
+			return false
+		}
+		switch sel := seg.sels[0]; sel.kind {
+		case qName, qWildcard:
+		case qIndex:
+			if sel.index < 0 {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// qeval evaluates queries over a document.
+type qeval[T Input] struct {
+	json T
+	root Result
+}
+
+// stream evaluates a streamable query in one pass over the document.
+// Each open container has a set of states, where a state is the position of
+// a segment that applies to the children of the container.
+func (q *qeval[T]) stream(segs []qsegment) ([]Result, error) {
+	d := -1 // position of the descendant segment
+	for p := range segs {
+		if segs[p].desc {
+			d = p
+		}
+	}
+	type frame struct {
+		start  int    // start of the container
+		states uint64 // segment positions for the children
+		arr    bool   // the container is an Array
+		n      int    // index of the next element, in an Array
+		res    int    // result for the container, or -1
+	}
+	// The results of a descendant segment are ordered by the value that the
+	// segment was applied to, which is 'di'.
+	type result struct {
+		di int
+		Result
+	}
+	var stack []frame
+	var results []result
+	var next uint64 // states for the children of the next value
+	final := -1     // position of the segment that selects the next value
+	_, err := ParseErr(q.json, 0, func(start, end, info int) int {
+		switch {
+		case info&Close == Close:
+			f := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if f.res >= 0 {
+				results[f.res].End = end
+			}
+			return 1
+		case info&Key == Key:
+			next, final = qstep(segs, stack[len(stack)-1].states, -1,
+				q.json[start:end], info)
+			return 1
+		case info&Start == Start:
+			next, final = 1, -1
+			if len(segs) == 0 {
+				next, final = 0, 0
+			}
+		case info&Value == 0:
+			// Comma or Colon
+			return 1
+		case stack[len(stack)-1].arr:
+			top := &stack[len(stack)-1]
+			var key T
+			next, final = qstep(segs, top.states, top.n, key, 0)
+			top.n++
+		}
+		res := -1
+		if final >= 0 {
+			res = len(results)
+			var di int
+			if d >= 0 {
+				di = stack[len(stack)-1-(final-d)].start
+			}
+			results = append(results, result{di,
+				Result{start, end, info &^ (Start | End | Open)}})
+		}
+		if info&Open == Open {
+			stack = append(stack, frame{start: start, states: next,
+				arr: info&Array == Array, res: res})
+			if next == 0 {
+				return -1
+			}
+		}
+		return 1
+	})
+	if err != nil {
+		return nil, err
+	}
+	if d >= 0 {
+		sort.SliceStable(results, func(i, j int) bool {
+			return results[i].di < results[j].di
+		})
+	}
+	out := make([]Result, len(results))
+	for i := range results {
+		out[i] = results[i].Result
+	}
+	return out, nil
+}
+
+// qstep returns the states for the children of a value, and the position of
+// the segment that selects the value, or -1. The value is the member of an
+// Object with the key token, or the element of an Array at the index.
+func qstep[T Input](segs []qsegment, states uint64, index int, key T,
+	info int,
+) (next uint64, final int) {
+	final = -1
+	for p := 0; p < len(segs) && states>>p != 0; p++ {
+		if states&(1<<p) == 0 {
+			continue
+		}
+		seg := &segs[p]
+		if seg.desc {
+			next |= 1 << p
+		}
+		var ok bool
+		switch sel := &seg.sels[0]; sel.kind {
+		case qWildcard:
+			ok = true
+		case qName:
+			ok = index < 0 && keyeq(key, info, sel.name)
+		case qIndex:
+			ok = index == sel.index
+		}
+		if ok {
+			if p+1 == len(segs) {
+				final = p
+			} else {
+				next |= 1 << (p + 1)
+			}
+		}
+	}
+	return next, final
+}
+
+// qchild is a member of an Object or an element of an Array.
+type qchild struct {
+	keyStart, keyEnd, keyInfo int // key of a member
+	val                       Result
+}
+
+// children returns the members of an Object or the elements of an Array.
+func (q *qeval[T]) children(node Result) []qchild {
+	if node.Info&(Object|Array) == 0 {
+		return nil
+	}
+	var kids []qchild
+	var key qchild
+	var mark int
+	base := node.Start
+	// The children of the children are skipped.
+	vdoc(q.json[node.Start:node.End], 0, func(start, end, info int) int {
+		switch {
+		case info&Key == Key:
+			key.keyStart, key.keyEnd, key.keyInfo = base+start, base+end, info
+		case info&(Value|Open) == Value|Open:
+			mark = start
+			return -1
+		case info&(Value|Close) == Value|Close:
+			start = mark
+			info &^= Close
+			fallthrough
+		case info&Value == Value:
+			key.val = Result{base + start, base + end, info}
+			kids = append(kids, key)
+		}
+		return 1
+	}, DefaultMaxDepth)
+	return kids
+}
+
+// run applies the segments to the nodes, and returns the selected nodes.
+func (q *qeval[T]) run(segs []qsegment, nodes []Result) []Result {
+	for i := range segs {
+		seg := &segs[i]
+		var out []Result
+		for _, node := range nodes {
+			if seg.desc {
+				q.descend(seg.sels, node, &out)
+			} else {
+				q.sel(seg.sels, node, q.children(node), &out)
+			}
+		}
+		nodes = out
+	}
+	return nodes
+}
+
+// descend applies the selectors to a node and its descendants.
+func (q *qeval[T]) descend(sels []qselector, node Result, out *[]Result) {
+	kids := q.children(node)
+	q.sel(sels, node, kids, out)
+	for _, kid := range kids {
+		if kid.val.Info&(Object|Array) != 0 {
+			q.descend(sels, kid.val, out)
+		}
+	}
+}
+
+// sel applies the selectors to the children of a node.
+func (q *qeval[T]) sel(sels []qselector, node Result, kids []qchild,
+	out *[]Result,
+) {
+	arr := node.Info&Array == Array
+	for i := range sels {
+		sel := &sels[i]
+		switch sel.kind {
+		case qName:
+			if arr {
+				continue
+			}
+			for _, kid := range kids {
+				if keyeq(q.json[kid.keyStart:kid.keyEnd], kid.keyInfo,
+					sel.name) {
+					*out = append(*out, kid.val)
+				}
+			}
+		case qWildcard:
+			for _, kid := range kids {
+				*out = append(*out, kid.val)
+			}
+		case qIndex:
+			index := sel.index
+			if index < 0 {
+				index += len(kids)
+			}
+			if arr && index >= 0 && index < len(kids) {
+				*out = append(*out, kids[index].val)
+			}
+		case qSlice:
+			if !arr || sel.step == 0 {
+				continue
+			}
+			n := len(kids)
+			norm := func(i int) int {
+				if i < 0 {
+					return n + i
+				}
+				return i
+			}
+			clamp := func(i, lo, hi int) int {
+				if i < lo {
+					return lo
+				}
+				if i > hi {
+					return hi
+				}
+				return i
+			}
+			start, end := 0, n
+			if sel.step < 0 {
+				start, end = n-1, -n-1
+			}
+			if sel.hasStart {
+				start = sel.index
+			}
+			if sel.hasEnd {
+				end = sel.end
+			}
+			if sel.step > 0 {
+				lo, hi := clamp(norm(start), 0, n), clamp(norm(end), 0, n)
+				for i := lo; i < hi; i += sel.step {
+					*out = append(*out, kids[i].val)
+				}
+			} else {
+				hi, lo := clamp(norm(start), -1, n-1), clamp(norm(end), -1, n-1)
+				for i := hi; lo < i; i += sel.step {
+					*out = append(*out, kids[i].val)
+				}
+			}
+		case qFilter:
+			for _, kid := range kids {
+				if q.test(sel.filter, kid.val) {
+					*out = append(*out, kid.val)
+				}
+			}
+		}
+	}
+}
+
+// test returns the result of a filter expression for the current node.
+func (q *qeval[T]) test(e *qexpr, cur Result) bool {
+	switch e.op {
+	case qOr:
+		return q.test(e.a, cur) || q.test(e.b, cur)
+	case qAnd:
+		return q.test(e.a, cur) && q.test(e.b, cur)
+	case qNot:
+		return !q.test(e.a, cur)
+	case qCmp:
+		a, b := q.operand(&e.l, cur), q.operand(&e.r, cur)
+		switch e.cmp {
+		case "==":
+			return q.equal(a, b)
+		case "!=":
+			return !q.equal(a, b)
+		case "<":
+			return qless(a, b)
+		case "<=":
+			return qless(a, b) || q.equal(a, b)
+		case ">":
+			return qless(b, a)
+		default:
+			return qless(b, a) || q.equal(a, b)
+		}
+	}
+	if e.l.kind == qQuery {
+		return len(q.query(&e.l, cur)) > 0
+	}
+	// match or search
+	a, b := q.operand(&e.l.args[0], cur), q.operand(&e.l.args[1], cur)
+	if a.kind != qStr || b.kind != qStr || e.l.bad {
+		return false
+	}
+	re := e.l.re
+	if re == nil {
+		var err error
+		if re, err = iregexp(b.str, e.l.name == "match"); err != nil {
+			return false
+		}
+	}
+	return re.MatchString(a.str)
+}
+
+// query returns the nodes selected by a query operand.
+func (q *qeval[T]) query(op *qoperand, cur Result) []Result {
+	if !op.rel {
+		cur = q.root
+	}
+	return q.run(op.segs, []Result{cur})
+}
+
+// operand returns the value of a comparable operand.
+func (q *qeval[T]) operand(op *qoperand, cur Result) qvalue {
+	switch op.kind {
+	case qLiteral:
+		return op.lit
+	case qQuery:
+		if nodes := q.query(op, cur); len(nodes) > 0 {
+			return q.value(nodes[0])
+		}
+		return qvalue{}
+	}
+	switch op.name {
+	case "length":
+		switch v := q.operand(&op.args[0], cur); v.kind {
+		case qStr:
+			return qvalue{kind: qNum,
+				num: float64(utf8.RuneCountInString(v.str))}
+		case qNode:
+			return qvalue{kind: qNum, num: float64(len(q.children(v.node)))}
+		}
+	case "count":
+		return qvalue{kind: qNum, num: float64(len(q.query(&op.args[0], cur)))}
+	case "value":
+		if nodes := q.query(&op.args[0], cur); len(nodes) == 1 {
+			return q.value(nodes[0])
+		}
+	}
+	return qvalue{}
+}
+
+// value returns the value of a node.
+func (q *qeval[T]) value(node Result) qvalue {
+	tok := q.json[node.Start:node.End]
+	switch {
+	case node.Info&Number == Number:
+		x, _ := ParseFloat64(tok, node.Info)
+		return qvalue{kind: qNum, num: x}
+	case node.Info&String == String:
+		str, _ := Unescape(tok)
+		return qvalue{kind: qStr, str: str}
+	case node.Info&(True|False) != 0:
+		return qvalue{kind: qBool, b: node.Info&True == True}
+	case node.Info&Null == Null:
+		return qvalue{kind: qNull}
+	}
+	return qvalue{kind: qNode, node: node}
+}
+
+// equal returns true if the values are equal, where Objects and Arrays are
+// compared by their contents.
+func (q *qeval[T]) equal(a, b qvalue) bool {
+	if a.kind != b.kind {
+		return false
+	}
+	switch a.kind {
+	case qBool:
+		return a.b == b.b
+	case qNum:
+		return a.num == b.num
+	case qStr:
+		return a.str == b.str
+	case qNode:
+		if a.node.Info&(Object|Array) != b.node.Info&(Object|Array) {
+			return false
+		}
+		akids, bkids := q.children(a.node), q.children(b.node)
+		if len(akids) != len(bkids) {
+			return false
+		}
+		if a.node.Info&Array == Array {
+			for i := range akids {
+				if !q.equal(q.value(akids[i].val), q.value(bkids[i].val)) {
+					return false
+				}
+			}
+			return true
+		}
+		for _, akid := range akids {
+			key, _ := Unescape(q.json[akid.keyStart:akid.keyEnd])
+			found := false
+			for _, bkid := range bkids {
+				if keyeq(q.json[bkid.keyStart:bkid.keyEnd], bkid.keyInfo,
+					key) {
+					if !q.equal(q.value(akid.val), q.value(bkid.val)) {
+						return false
+					}
+					found = true
+					break
+				}
+			}
+			if !found {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// qless returns true if both values are Numbers or Strings, and 'a' is less
+// than 'b'.
+func qless(a, b qvalue) bool {
+	switch {
+	case a.kind == qNum && b.kind == qNum:
+		return a.num < b.num
+	case a.kind == qStr && b.kind == qStr:
+		return a.str < b.str
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package pjson
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// The example documents from RFC 9535.
+var (
+	queryStore = `{ "store": {
+    "book": [
+      { "category": "reference",
+        "author": "Nigel Rees",
+        "title": "Sayings of the Century",
+        "price": 8.95
+      },
+      { "category": "fiction",
+        "author": "Evelyn Waugh",
+        "title": "Sword of Honour",
+        "price": 12.99
+      },
+      { "category": "fiction",
+        "author": "Herman Melville",
+        "title": "Moby Dick",
+        "isbn": "0-553-21311-3",
+        "price": 8.99
+      },
+      { "category": "fiction",
+        "author": "J. R. R. Tolkien",
+        "title": "The Lord of the Rings",
+        "isbn": "0-395-19395-8",
+        "price": 22.99
+      }
+    ],
+    "bicycle": {
+      "color": "red",
+      "price": 399
+    }
+  }
+}`
+	queryFilter = `{
+  "a": [3, 5, 1, 2, 4, 6, {"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}],
+  "o": {"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}},
+  "e": "f"
+}`
+	queryDesc = `{"o": {"j": 1, "k": 2}, "a": [5, 3, [{"j": 4}, {"k": 6}]]}`
+)
+
+// testQuery checks the values selected by a query, which are separated by
+// "|" in 'expect'.
+func testQuery(t *testing.T, json, path, expect string) {
+	t.Helper()
+	for _, input := range []string{"string", "bytes"} {
+		var results []Result
+		var err error
+		if input == "string" {
+			results, err = Query(json, path)
+		} else {
+			results, err = Query([]byte(json), path)
+		}
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		var vals []string
+		for _, r := range results {
+			val := json[r.Start:r.End]
+			vals = append(vals, val)
+			if _, _, info := Get(val, ""); info != r.Info&^Value {
+				t.Fatalf("%s: expected info %d, got %d", path, info, r.Info)
+			}
+		}
+		if got := strings.Join(vals, "|"); got != expect {
+			t.Fatalf("%s: expected\n%s\ngot\n%s", path, expect, got)
+		}
+	}
+	// Streamable queries get the same results from the general evaluator.
+	segs, _ := compileQuery(path)
+	if streamable(segs) {
+		results1, _ := Query(json, path)
+		q := &qeval[string]{json: json}
+		start, end, info := Get(json, "")
+		q.root = Result{start, end, info}
+		results2 := q.run(segs, []Result{q.root})
+		if len(results1) != len(results2) {
+			t.Fatalf("%s: stream mismatch %v %v", path, results1, results2)
+		}
+		for i := range results1 {
+			if results1[i] != results2[i] {
+				t.Fatalf("%s: stream mismatch %v %v", path, results1,
+					results2)
+			}
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	authors := `"Nigel Rees"|"Evelyn Waugh"|"Herman Melville"|` +
+		`"J. R. R. Tolkien"`
+	testQuery(t, queryStore, `$.store.book[*].author`, authors)
+	testQuery(t, queryStore, `$..author`, authors)
+	testQuery(t, queryStore, `$..book[*]["author"]`, authors)
+	testQuery(t, queryStore, `$.store..price`, `8.95|12.99|8.99|22.99|399`)
+	testQuery(t, queryStore, `$..book[2].title`, `"Moby Dick"`)
+	testQuery(t, queryStore, `$..book[-1].title`, `"The Lord of the Rings"`)
+	testQuery(t, queryStore, `$..book[0,1].title`,
+		`"Sayings of the Century"|"Sword of Honour"`)
+	testQuery(t, queryStore, `$..book[:2].title`,
+		`"Sayings of the Century"|"Sword of Honour"`)
+	testQuery(t, queryStore, `$..book[?@.isbn].title`,
+		`"Moby Dick"|"The Lord of the Rings"`)
+	testQuery(t, queryStore, `$.store.book[?@.price < 10].title`,
+		`"Sayings of the Century"|"Moby Dick"`)
+	testQuery(t, queryStore, `$.store.bicycle`,
+		`{
+      "color": "red",
+      "price": 399
+    }`)
+	testQuery(t, queryStore, `$.store.bicycle.color`, `"red"`)
+	testQuery(t, queryStore, `$.store.bicycle.color.x`, ``)
+	testQuery(t, queryStore, `$.store.book[4]`, ``)
+	testQuery(t, queryStore, `$.store.book.author`, ``)
+	testQuery(t, queryStore, `$[0]`, ``)
+	testQuery(t, queryStore, `$`, queryStore)
+	testQuery(t, ` [1, 2] `, `$`, `[1, 2]`)
+	testQuery(t, ` 1 `, `$`, `1`)
+	testQuery(t, ` 1 `, `$.a`, ``)
+	testQuery(t, ` 1 `, `$[?@]`, ``)
+
+	// Filters
+	testQuery(t, queryFilter, `$.a[?@.b == 'kilo']`, `{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?(@.b == 'kilo')]`, `{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?@>3.5]`, `5|4|6`)
+	testQuery(t, queryFilter, `$.a[?@.b]`,
+		`{"b": "j"}|{"b": "k"}|{"b": {}}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$[?@.*]`, `[3, 5, 1, 2, 4, 6, {"b": "j"}, `+
+		`{"b": "k"}, {"b": {}}, {"b": "kilo"}]|{"p": 1, "q": 2, "r": 3, `+
+		`"s": 5, "t": {"u": 6}}`)
+	testQuery(t, queryFilter, `$[?@[?@.b]]`, `[3, 5, 1, 2, 4, 6, `+
+		`{"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]`)
+	testQuery(t, queryFilter, `$.o[?@<3, ?@<3]`, `1|2|1|2`)
+	testQuery(t, queryFilter, `$.a[?@<2 || @.b == "k"]`, `1|{"b": "k"}`)
+	testQuery(t, queryFilter, `$.a[?match(@.b, "[jk]")]`,
+		`{"b": "j"}|{"b": "k"}`)
+	testQuery(t, queryFilter, `$.a[?search(@.b, "[jk]")]`,
+		`{"b": "j"}|{"b": "k"}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.o[?@>1 && @<4]`, `2|3`)
+	testQuery(t, queryFilter, `$.o[?@.u || @.x]`, `{"u": 6}`)
+	testQuery(t, queryFilter, `$.a[?@.b == $.x]`, `3|5|1|2|4|6`)
+	testQuery(t, queryFilter, `$.a[?@ == @]`, `3|5|1|2|4|6|{"b": "j"}|`+
+		`{"b": "k"}|{"b": {}}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?!@.b]`, `3|5|1|2|4|6`)
+	testQuery(t, queryFilter, `$.a[?!(@ > 2)]`, `1|2|{"b": "j"}|`+
+		`{"b": "k"}|{"b": {}}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?@ != 1 && @ <= 3]`, `3|2`)
+	testQuery(t, queryFilter, `$.a[?@ >= 5]`, `5|6`)
+	testQuery(t, queryFilter, `$.a[?@.b >= 'k']`, `{"b": "k"}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?@.b == $.a[8].b]`, `{"b": {}}`)
+	testQuery(t, queryFilter, `$[?@.x == $.y]`, `[3, 5, 1, 2, 4, 6, `+
+		`{"b": "j"}, {"b": "k"}, {"b": {}}, {"b": "kilo"}]|{"p": 1, "q": 2, `+
+		`"r": 3, "s": 5, "t": {"u": 6}}|"f"`)
+	testQuery(t, queryFilter, `$[?@ == $.e]`, `"f"`)
+	testQuery(t, `[{"a":[1,{"b":2}]},{"a":[1,{"b":2}]},{"a":[1,{"b":3}]},`+
+		`{"a":[{"b":2},1]}]`, `$[?@.a == $[0].a]`,
+		`{"a":[1,{"b":2}]}|{"a":[1,{"b":2}]}`)
+	testQuery(t, `[{"a":{"x":1,"y":[]}},{"a":{"y":[],"x":1}},`+
+		`{"a":{"x":1}},{"a":{"x":1,"z":[]}}]`, `$[?@.a == $[0].a]`,
+		`{"a":{"x":1,"y":[]}}|{"a":{"y":[],"x":1}}`)
+	testQuery(t, `[true,false,null,"true",1,1.0,1e0,"a"]`,
+		`$[?@ == true || @ == null || @ == 1 || @ == 'a']`,
+		`true|null|1|1.0|1e0|"a"`)
+	testQuery(t, `[-1, 0, 1]`, `$[?@ < -0 || @ == -0.0e0]`, `-1|0`)
+
+	// Functions
+	testQuery(t, `["ab","é",[1,2,3],{"a":1},1]`, `$[?length(@) == 1]`,
+		`"é"|{"a":1}`)
+	testQuery(t, queryFilter, `$[?count(@.*) == 5]`,
+		`{"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}`)
+	testQuery(t, queryFilter, `$.a[?length(@.b) > 1]`, `{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$[?value(@..u) == 6]`,
+		`{"p": 1, "q": 2, "r": 3, "s": 5, "t": {"u": 6}}`)
+	testQuery(t, queryFilter, `$[?value(@.*) == 6]`, ``)
+	testQuery(t, queryFilter, `$.a[?match(@.b, 'k.*')]`,
+		`{"b": "k"}|{"b": "kilo"}`)
+	testQuery(t, queryFilter, `$.a[?match(@.b, $.e)]`, ``)
+	testQuery(t, queryFilter, `$.a[?search(@.b, '(')]`, ``)
+	testQuery(t, `["a\nb","a\rb","axb"]`, `$[?match(@, 'a.b')]`, `"axb"`)
+	testQuery(t, `["a.b","axb"]`, `$[?match(@, 'a[.]b')]`, `"a.b"`)
+	testQuery(t, `["a.b","axb"]`, `$[?match(@, 'a\\.b')]`, `"a.b"`)
+
+	// Slices and indexes
+	abc := `["a","b","c","d","e","f","g"]`
+	testQuery(t, abc, `$[1:3]`, `"b"|"c"`)
+	testQuery(t, abc, `$[5:]`, `"f"|"g"`)
+	testQuery(t, abc, `$[1:5:2]`, `"b"|"d"`)
+	testQuery(t, abc, `$[5:1:-2]`, `"f"|"d"`)
+	testQuery(t, abc, `$[::-1]`, `"g"|"f"|"e"|"d"|"c"|"b"|"a"`)
+	testQuery(t, abc, `$[ : : 0]`, ``)
+	testQuery(t, abc, `$[-2:]`, `"f"|"g"`)
+	testQuery(t, abc, `$[-100:2]`, `"a"|"b"`)
+	testQuery(t, abc, `$[:-100:-3]`, `"g"|"d"|"a"`)
+	testQuery(t, abc, `$[1]`, `"b"`)
+	testQuery(t, abc, `$[-2]`, `"f"`)
+	testQuery(t, abc, `$[-8]`, ``)
+	testQuery(t, abc, `$[0, 0, -1]`, `"a"|"a"|"g"`)
+	testQuery(t, abc, `$[1:2, 'a', *][0]`, ``)
+
+	// Names
+	testQuery(t, `{"a'b":1,"a\"b":2,"é":3,"a":{"b c":4},"_9":5}`,
+		`$['a\'b', "a\"b", 'é', "é", 'a']['b c']`, `4`)
+	testQuery(t, `{"a'b":1,"a\"b":2,"é":3,"a":{"b c":4},"_9":5}`,
+		`$['a\'b', "a\"b", 'é', "é"]`, `1|2|3|3`)
+	testQuery(t, `{"é":1,"_9":2,"😀":3}`, `$.é`, `1`)
+	testQuery(t, `{"é":1,"_9":2,"😀":3}`, `$._9`, `2`)
+	testQuery(t, `{"é":1,"_9":2,"😀":3}`, `$["😀"]`, `3`)
+	testQuery(t, `{"a":1,"a":2}`, `$.a`, `1|2`)
+	testQuery(t, `{"a":{"b":1}}`, `$ .a [ 'b' ]`, `1`)
+
+	// Descendants
+	testQuery(t, queryDesc, `$..j`, `1|4`)
+	testQuery(t, queryDesc, `$..[0]`, `5|{"j": 4}`)
+	all := `{"j": 1, "k": 2}|[5, 3, [{"j": 4}, {"k": 6}]]|1|2|5|3|` +
+		`[{"j": 4}, {"k": 6}]|{"j": 4}|{"k": 6}|4|6`
+	testQuery(t, queryDesc, `$..[*]`, all)
+	testQuery(t, queryDesc, `$..*`, all)
+	testQuery(t, queryDesc, `$..o`, `{"j": 1, "k": 2}`)
+	testQuery(t, queryDesc, `$.o..[*, *]`, `1|2|1|2`)
+	testQuery(t, queryDesc, `$.a..[0, 1]`, `5|3|{"j": 4}|{"k": 6}`)
+	testQuery(t, queryDesc, `$..*.j`, `1|4`)
+	testQuery(t, queryDesc, `$..*..j`, `1|4|4|4`)
+	testQuery(t, queryDesc, `$.a[2]..k`, `6`)
+	testQuery(t, queryDesc, `$..[2][1]`, `{"k": 6}`)
+	testQuery(t, `[[[1]]]`, `$..[0]`, `[[1]]|[1]|1`)
+	testQuery(t, `{"a":{"a":{"a":1}}}`, `$..a.a`, `{"a":1}|1`)
+}
+
+func TestQueryErrors(t *testing.T) {
+	for _, path := range []string{
+		``, `a`, `$.`, `$..`, `$.1`, `$[`, `$[1`, `$['a]`, `$["a\z"]`,
+		`$[01]`, `$[-0]`, `$[-]`, `$[1.0]`, `$[9007199254740992]`,
+		`$[?@.a == ]`, `$[?1]`, `$[?'a']`, `$[?true]`, `$[?@.* == 1]`,
+		`$[?@..a == 1]`, `$[?length(@.*) < 3]`, `$[?length(@)]`,
+		`$[?match(@.a, 'x') == true]`, `$[?foo(@)]`, `$[?count(1) == 1]`,
+		`$[?value(@.a, @.b) == 1]`, `$[?!@.a == 1]`, `$[?(@.a]`, `$ `,
+		`$.a b`, `$[1 2]`, `$[?@.a == 01]`, `$[?@.a == 1.]`, `$['\ud800']`,
+		`$['\udc00']`, "$['\x01']", "$['\xff']", `$[?@ = 1]`, `$[?@ == -]`,
+		`$[?@ == nul]`, `$[?@ == {}]`, `$[1:`, `$[:`, `$ . a`,
+	} {
+		var qerr *QueryError
+		if _, err := Query(`{}`, path); !errors.As(err, &qerr) {
+			t.Fatalf("%q: expected a QueryError, got %v", path, err)
+		}
+	}
+	var serr *SyntaxError
+	if _, err := Query(`{"a":[1,}`, `$.b`); !errors.As(err, &serr) {
+		t.Fatalf("expected a SyntaxError, got %v", err)
+	}
+	if _, err := Query(`{"a":[1,}`, `$[?@]`); !errors.As(err, &serr) {
+		t.Fatalf("expected a SyntaxError, got %v", err)
+	}
+	_, err := Query(`{}`, `$.a[?@.b == ]`)
+	if err.Error() != "pjson: invalid query at offset 12: expected an operand" {
+		t.Fatalf("got %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+// DefaultMaxDepth is the maximum nesting depth of the Objects and Arrays in a
+// document, unless a different maximum is provided with Limits.
+const DefaultMaxDepth = 10000
+
+// Limits are resource limits for parsing documents from untrusted sources.
+// Exceeding a limit is a *SyntaxError with a kind that is specific to the
+// limit, at the offset of the element that exceeds the limit.
+// The limits other than MaxDepth are not checked when they are zero.
+type Limits struct {
+	// MaxDepth is the maximum nesting depth of Objects and Arrays.
+	// Zero means DefaultMaxDepth, and a negative value means no limit, which
+	// should only be used for documents from trusted sources.
+	MaxDepth int
+	// MaxBytes is the maximum size of the document in bytes. The elements
+	// before the limit are parsed as usual, and the document fails with
+	// DocumentTooLarge at the offset MaxBytes.
+	MaxBytes int
+	// MaxTokens is the maximum number of elements, including all values and
+	// tokens. See Parse.
+	MaxTokens int
+	// MaxStringLen is the maximum length in bytes of a String or key,
+	// without the quotes and before unescaping.
+	MaxStringLen int
+	// MaxNumberLen is the maximum length in bytes of a Number, which is
+	// about the number of digits.
+	MaxNumberLen int
+	// MaxMembers is the maximum number of members in one Object, or elements
+	// in one Array.
+	MaxMembers int
+}
+
+// maxDepth returns the maximum nesting depth, or -1 for no limit.
+func (limits *Limits) maxDepth() int {
+	if limits.MaxDepth == 0 {
+		return DefaultMaxDepth
+	}
+	if limits.MaxDepth < 0 {
+		return -1
+	}
+	return limits.MaxDepth
+}
+
+// ParseLimits parses JSON like ParseErr, but it fails with a *SyntaxError
+// when the document exceeds a limit.
+func ParseLimits[T Input](json T, opts int, limits Limits,
+	iter func(start, end, info int) int,
+) (int, error) {
+	depth := limits.maxDepth()
+	if opts != 0 || depth < 0 ||
+		limits != (Limits{MaxDepth: limits.MaxDepth}) {
+		// Only the stream checks the limits other than MaxDepth, and only
+		// the stream has no recursion for documents without a depth limit.
+		var s stream[T]
+		s.limits = limits
+		s.iter = iter
+		n := s.parse(json, opts)
+		if n < 0 {
+			n = -n
+		}
+		return n, s.err()
+	}
+	i, ok, _ := vdoc(json, 0, iter, depth)
+	if ok {
+		return i, nil
+	}
+	return syntaxErr(json, limits)
+}
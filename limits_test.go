@@ -0,0 +1,210 @@
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func testdepth(t *testing.T, json string, opts, max, expect int) {
+	t.Helper()
+	n, err := ParseLimits([]byte(json), opts, Limits{MaxDepth: max}, nil)
+	var serr *SyntaxError
+	if expect < 0 {
+		if err != nil || n != len(json) {
+			t.Fatalf("expected %d, got %d %v", len(json), n, err)
+		}
+		return
+	}
+	if !errors.As(err, &serr) || serr.Kind != DepthExceeded ||
+		serr.Offset != expect || n != expect {
+		t.Fatalf("expected %v at %d, got %d %v", DepthExceeded, expect, n, err)
+	}
+	p := NewParser(opts, nil)
+	p.SetLimits(Limits{MaxDepth: max})
+	p.Write([]byte(json))
+	if err := p.Close(); err == nil || err.Error() != serr.Error() {
+		t.Fatalf("expected %v, got %v", serr, err)
+	}
+}
+
+func TestMaxDepth(t *testing.T) {
+	for _, opts := range []int{0, AllowComments} {
+		testdepth(t, `[[[[]]]]`, opts, 4, -1)
+		testdepth(t, `[[[[[]]]]]`, opts, 4, 4)
+		testdepth(t, `[{"a":[{}]}]`, opts, 4, -1)
+		testdepth(t, `[{"a":[{"b":{}}]}]`, opts, 4, 12)
+		testdepth(t, `{"a":1,"b":[1,2,3],"c":{"d":[]}}`, opts, 3, -1)
+		testdepth(t, `{"a":1,"b":[1,2,3],"c":{"d":[[]]}}`, opts, 3, 29)
+		testdepth(t, `1`, opts, 1, -1)
+
+		deep := strings.Repeat("[", DefaultMaxDepth) +
+			strings.Repeat("]", DefaultMaxDepth)
+		testdepth(t, deep, opts, 0, -1)
+		testdepth(t, "["+deep+"]", opts, 0, DefaultMaxDepth)
+		testdepth(t, "["+deep+"]", opts, DefaultMaxDepth+1, -1)
+
+		// Adversarial input that would exhaust the stack without a limit.
+		json := []byte(strings.Repeat("[", 4000000))
+		if n := Parse(json, opts, nil); n != -DefaultMaxDepth {
+			t.Fatalf("expected %d, got %d", -DefaultMaxDepth, n)
+		}
+		testdepth(t, string(json), opts, 100, 100)
+	}
+
+	// No limit for the stream parsers, which use an explicit stack, and
+	// which are also used without a limit when there are no options.
+	json := []byte(strings.Repeat("[", 4000000))
+	for _, opts := range []int{0, AllowComments} {
+		n, err := ParseLimits(json, opts, Limits{MaxDepth: -1}, nil)
+		var serr *SyntaxError
+		if !errors.As(err, &serr) || serr.Kind != UnexpectedEOF ||
+			n != len(json) {
+			t.Fatalf("expected %v, got %v", UnexpectedEOF, err)
+		}
+	}
+	tz := NewTokenizer(json, 0)
+	tz.SetLimits(Limits{MaxDepth: -1})
+	tz.Next()
+	var serr *SyntaxError
+	if err := tz.Skip(); !errors.As(err, &serr) ||
+		serr.Kind != UnexpectedEOF {
+		t.Fatalf("expected %v, got %v", UnexpectedEOF, err)
+	}
+}
+
+func testlimits(t *testing.T, json string, opts int, limits Limits,
+	kind ErrorKind, offset int,
+) {
+	t.Helper()
+	n, err := ParseLimits([]byte(json), opts, limits, nil)
+	var serr *SyntaxError
+	if kind == 0 {
+		if err != nil || n != len(json) {
+			t.Fatalf("%q: expected %d, got %d %v", json, len(json), n, err)
+		}
+	} else if !errors.As(err, &serr) || serr.Kind != kind ||
+		serr.Offset != offset || n != offset {
+		t.Fatalf("%q: expected %v at %d, got %d %v", json, kind, offset, n,
+			err)
+	}
+	for _, r := range []io.Reader{strings.NewReader(json),
+		iotest.OneByteReader(strings.NewReader(json))} {
+		n2, err2 := ParseReaderLimits(r, opts, limits, nil)
+		if fmt.Sprint(err2) != fmt.Sprint(err) || n2 != n {
+			t.Fatalf("%q: expected %v at %d, got %v at %d", json, err, n,
+				err2, n2)
+		}
+	}
+	if err != nil {
+		n = -n
+	}
+	for _, size := range []int{1, 3, len(json)} {
+		p := NewParser(opts, nil)
+		p.SetLimits(limits)
+		for i := 0; i < len(json); i += size {
+			end := i + size
+			if end > len(json) {
+				end = len(json)
+			}
+			if _, err := p.Write([]byte(json[i:end])); err != nil {
+				break
+			}
+		}
+		if err2 := p.Close(); fmt.Sprint(err2) != fmt.Sprint(err) ||
+			p.Offset() != n {
+			t.Fatalf("%q: expected %v, got %v", json, err, err2)
+		}
+	}
+}
+
+// countReader returns an endless Array of zeros and counts the bytes that
+// are read.
+type countReader struct {
+	n int
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	for i := range p {
+		if r.n+i == 0 {
+			p[i] = '['
+		} else if (r.n+i)%2 == 1 {
+			p[i] = '0'
+		} else {
+			p[i] = ','
+		}
+	}
+	r.n += len(p)
+	return len(p), nil
+}
+
+func TestLimits(t *testing.T) {
+	limits := Limits{MaxBytes: 10}
+	testlimits(t, `[1,2,3]`, 0, limits, 0, 0)
+	testlimits(t, `[1,2,3,45]`, 0, limits, 0, 0)
+	testlimits(t, `[1,2,3,4,5,6]`, 0, limits, DocumentTooLarge, 10)
+	testlimits(t, `"0123456789"`, 0, limits, DocumentTooLarge, 10)
+	testlimits(t, `1234567890   `, 0, limits, DocumentTooLarge, 10)
+	testlimits(t, `[1, 2]     `, AllowComments, limits, DocumentTooLarge, 10)
+
+	limits = Limits{MaxTokens: 5}
+	testlimits(t, `[1,2]`, 0, limits, 0, 0)
+	testlimits(t, `{"a":1}`, 0, limits, 0, 0)
+	testlimits(t, `[1,2,3]`, 0, limits, TooManyTokens, 5)
+	testlimits(t, `[[],[]]`, 0, limits, TooManyTokens, 5)
+
+	limits = Limits{MaxStringLen: 3}
+	testlimits(t, `["abc",""]`, 0, limits, 0, 0)
+	testlimits(t, `{"abc":"ab"}`, 0, limits, 0, 0)
+	testlimits(t, `["abc","abcd"]`, 0, limits, StringTooLong, 7)
+	testlimits(t, `{"abcd":1}`, 0, limits, StringTooLong, 1)
+	testlimits(t, `["\n\n"]`, 0, limits, StringTooLong, 1)
+	testlimits(t, `{abc:'abc'}`, AllowJSON5, limits, 0, 0)
+	testlimits(t, `{abcd:1}`, AllowJSON5, limits, StringTooLong, 1)
+	testlimits(t, `{a:'abcd'}`, AllowJSON5, limits, StringTooLong, 3)
+	testlimits(t, `["abcd`, 0, limits, StringTooLong, 1)
+
+	limits = Limits{MaxNumberLen: 3}
+	testlimits(t, `[123,-12,1.5,1e9]`, 0, limits, 0, 0)
+	testlimits(t, `[1234]`, 0, limits, NumberTooLong, 1)
+	testlimits(t, `[0,-123]`, 0, limits, NumberTooLong, 3)
+	testlimits(t, `1e100`, 0, limits, NumberTooLong, 0)
+	testlimits(t, `[0xF,0x1F]`, AllowJSON5, limits, NumberTooLong, 5)
+
+	limits = Limits{MaxMembers: 2}
+	testlimits(t, `{"a":[1,2],"b":{"c":[[],{}]}}`, 0, limits, 0, 0)
+	testlimits(t, `[1,2,3]`, 0, limits, TooManyMembers, 5)
+	testlimits(t, `{"a":1,"b":2,"c":3}`, 0, limits, TooManyMembers, 13)
+	testlimits(t, `[[1,2],[3],[4]]`, 0, limits, TooManyMembers, 11)
+	testlimits(t, `[{"a":1,"b":2,"c":3}]`, 0, limits, TooManyMembers, 14)
+	testlimits(t, `[{},[],{"a":[]}]`, 0, limits, TooManyMembers, 7)
+	testlimits(t, `[1,2,]`, AllowJSON5, limits, 0, 0)
+
+	// The reader is not read past the MaxBytes limit, such that an endless
+	// document fails.
+	r := &countReader{}
+	n, err := ParseReaderLimits(r, 0, Limits{MaxBytes: 100}, nil)
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Kind != DocumentTooLarge || n != 100 ||
+		r.n > 101 {
+		t.Fatalf("expected %v at 100, got %v at %d, after reading %d",
+			DocumentTooLarge, err, n, r.n)
+	}
+
+	// Limits are checked for skipped children.
+	n, err = ParseLimits([]byte(`[[1,2,3]]`), 0, Limits{MaxMembers: 2},
+		func(start, end, info int) int { return -1 })
+	if !errors.As(err, &serr) || serr.Kind != TooManyMembers || n != 6 {
+		t.Fatalf("expected %v at 6, got %d %v", TooManyMembers, n, err)
+	}
+
+	// A long string fails before the Parser has all of it.
+	p := NewParser(0, nil)
+	p.SetLimits(Limits{MaxStringLen: 100})
+	if _, err := p.Write([]byte(`["` + strings.Repeat("a", 200))); err == nil {
+		t.Fatal("expected an error")
+	}
+}
@@ -0,0 +1,259 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"errors"
+	"math"
+	"strconv"
+)
+
+var (
+	// ErrOverflow is returned when a Number is out of range for its type.
+	ErrOverflow = errors.New("pjson: number out of range")
+	// ErrNotInteger is returned when a Number with a fraction, exponent or
+	// that is Infinity or NaN is parsed as an integer.
+	ErrNotInteger = errors.New("pjson: number is not an integer")
+)
+
+// ParseInt64 returns the value of a Number token, such as json[start:end] when
+// the 'info' from Parse has the Number bit set.
+// The 'info' is used to skip unneeded work. It returns ErrNotInteger if the
+// number has a fraction or exponent, and ErrOverflow if the number does not
+// fit in an int64.
+func ParseInt64[T Input](token T, info int) (int64, error) {
+	x, err := parseUint64(token, info)
+	if err != nil {
+		return 0, err
+	}
+	if info&Sign == Sign {
+		if x > 1<<63 {
+			return 0, ErrOverflow
+		}
+		return -int64(x), nil
+	}
+	if x > math.MaxInt64 {
+		return 0, ErrOverflow
+	}
+	return int64(x), nil
+}
+
+// ParseUint64 returns the value of a Number token, like ParseInt64, but it
+// returns ErrOverflow for all negative numbers other than zero.
+func ParseUint64[T Input](token T, info int) (uint64, error) {
+	x, err := parseUint64(token, info)
+	if err != nil {
+		return 0, err
+	}
+	if info&Sign == Sign && x != 0 {
+		return 0, ErrOverflow
+	}
+	return x, nil
+}
+
+// digits returns the token without its sign, if any.
+func digits[T Input](token T) T {
+	if len(token) > 0 && (token[0] == '-' || token[0] == '+') {
+		return token[1:]
+	}
+	return token
+}
+
+// parseUint64 returns the absolute value of an integer token.
+func parseUint64[T Input](token T, info int) (uint64, error) {
+	if info&(Dot|E|Infinity|NaN) != 0 {
+		return 0, ErrNotInteger
+	}
+	token = digits(token)
+	if info&Hex == Hex {
+		if len(token) < 3 {
+			return 0, ErrInvalid
+		}
+		token = token[2:]
+		if len(token) > 16 {
+			return 0, ErrOverflow
+		}
+		x, ok := unhex64(token)
+		if !ok {
+			return 0, ErrInvalid
+		}
+		return x, nil
+	}
+	if len(token) == 0 {
+		return 0, ErrInvalid
+	}
+	var x uint64
+	if len(token) < 20 {
+		// Up to 19 digits always fit, so there's no need to check for
+		// overflow.
+		for i := 0; i < len(token); i++ {
+			ch := token[i]
+			if !isnum(ch) {
+				return 0, ErrInvalid
+			}
+			x = x*10 + uint64(ch-'0')
+		}
+		return x, nil
+	}
+	for i := 0; i < len(token); i++ {
+		ch := token[i]
+		if !isnum(ch) {
+			return 0, ErrInvalid
+		}
+		if x > math.MaxUint64/10 {
+			return 0, ErrOverflow
+		}
+		x = x * 10
+		if x+uint64(ch-'0') < x {
+			return 0, ErrOverflow
+		}
+		x += uint64(ch - '0')
+	}
+	return x, nil
+}
+
+func unhex64[T Input](token T) (uint64, bool) {
+	var x uint64
+	for i := 0; i < len(token); i++ {
+		ch := token[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+			ch -= '0'
+		case ch >= 'a' && ch <= 'f':
+			ch -= 'a' - 10
+		case ch >= 'A' && ch <= 'F':
+			ch -= 'A' - 10
+		default:
+			return 0, false
+		}
+		x = x<<4 | uint64(ch)
+	}
+	return x, true
+}
+
+// float64pow10 are the powers of ten that are exactly representable as a
+// float64.
+var float64pow10 = [...]float64{
+	1e0, 1e1, 1e2, 1e3, 1e4, 1e5, 1e6, 1e7, 1e8, 1e9, 1e10, 1e11, 1e12, 1e13,
+	1e14, 1e15, 1e16, 1e17, 1e18, 1e19, 1e20, 1e21, 1e22,
+}
+
+// ParseFloat64 returns the value of a Number token, such as json[start:end]
+// when the 'info' from Parse has the Number bit set.
+// The result is correctly rounded. The 'info' is used to skip unneeded work,
+// such as for integers with up to 15 digits, which are converted directly.
+// Most other numbers are converted with the exact Clinger fast path, while
+// the rest fall back to strconv.ParseFloat, which uses the Eisel-Lemire
+// algorithm.
+// It returns ErrOverflow, along with an infinity, when the number is too
+// large for a float64.
+func ParseFloat64[T Input](token T, info int) (float64, error) {
+	neg := info&Sign == Sign
+	f, err := parseFloat64(token, info)
+	if neg {
+		f = -f
+	}
+	return f, err
+}
+
+// parseFloat64 returns the absolute value of a token.
+func parseFloat64[T Input](token T, info int) (float64, error) {
+	if info&Infinity == Infinity {
+		return math.Inf(1), nil
+	}
+	if info&NaN == NaN {
+		return math.NaN(), nil
+	}
+	if info&(Dot|E|Hex) == 0 && len(digits(token)) <= 15 {
+		// Integers with up to 15 digits are exact.
+		x, err := parseUint64(token, info)
+		return float64(x), err
+	}
+	token = digits(token)
+	if info&Hex == Hex {
+		if len(token) > 2 && len(token)-2 <= 13 {
+			// Up to 13 hex digits (52 bits) are exact.
+			x, ok := unhex64(token[2:])
+			if ok {
+				return float64(x), nil
+			}
+		}
+		return slowFloat64(string(token) + "p0")
+	}
+	// Read up to 19 significant digits into the mantissa, and the decimal
+	// exponent.
+	var mant uint64
+	var exp, ndigits int
+	var valid bool
+	i := 0
+	for ; i < len(token) && isnum(token[i]); i++ {
+		valid = true
+		if mant == 0 && token[i] == '0' {
+			continue
+		}
+		mant = mant*10 + uint64(token[i]-'0')
+		ndigits++
+	}
+	if i < len(token) && token[i] == '.' {
+		for i++; i < len(token) && isnum(token[i]); i++ {
+			valid = true
+			exp--
+			if mant == 0 && token[i] == '0' {
+				continue
+			}
+			mant = mant*10 + uint64(token[i]-'0')
+			ndigits++
+		}
+	}
+	if ndigits > 19 {
+		return slowFloat64(string(token))
+	}
+	if i < len(token) && (token[i] == 'e' || token[i] == 'E') {
+		i++
+		eneg := false
+		if i < len(token) && (token[i] == '-' || token[i] == '+') {
+			eneg = token[i] == '-'
+			i++
+		}
+		var e int
+		for ; i < len(token) && isnum(token[i]); i++ {
+			if e < 10000 {
+				e = e*10 + int(token[i]-'0')
+			}
+		}
+		if eneg {
+			e = -e
+		}
+		exp += e
+	}
+	if i != len(token) || !valid {
+		return 0, ErrInvalid
+	}
+	if mant == 0 {
+		return 0, nil
+	}
+	if mant <= 1<<53 {
+		// Clinger's fast path, which is exact because both the mantissa and
+		// the power of ten are exactly representable.
+		if exp >= 0 && exp <= 22 {
+			return float64(mant) * float64pow10[exp], nil
+		}
+		if exp < 0 && exp >= -22 {
+			return float64(mant) / float64pow10[-exp], nil
+		}
+	}
+	return slowFloat64(string(token))
+}
+
+func slowFloat64(s string) (float64, error) {
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		if err.(*strconv.NumError).Err == strconv.ErrRange {
+			return f, ErrOverflow
+		}
+		return 0, ErrInvalid
+	}
+	return f, nil
+}
@@ -0,0 +1,149 @@
+package pjson
+
+import (
+	"math"
+	"math/rand"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// numinfo returns the info for a Number token.
+func numinfo(t *testing.T, token string, opts int) int {
+	t.Helper()
+	var ninfo int
+	if Parse([]byte(token), opts, func(start, end, info int) int {
+		ninfo = info
+		return 1
+	}) != len(token) || ninfo&Number == 0 {
+		t.Fatalf("%s: invalid number", token)
+	}
+	return ninfo
+}
+
+func TestParseInt64(t *testing.T) {
+	for _, tc := range []struct {
+		token  string
+		opts   int
+		expect int64
+		err    error
+	}{
+		{"0", 0, 0, nil},
+		{"-0", 0, 0, nil},
+		{"123", 0, 123, nil},
+		{"-123", 0, -123, nil},
+		{"9223372036854775807", 0, math.MaxInt64, nil},
+		{"9223372036854775808", 0, 0, ErrOverflow},
+		{"-9223372036854775808", 0, math.MinInt64, nil},
+		{"-9223372036854775809", 0, 0, ErrOverflow},
+		{"18446744073709551616", 0, 0, ErrOverflow},
+		{"123456789012345678901234567890", 0, 0, ErrOverflow},
+		{"1.0", 0, 0, ErrNotInteger},
+		{"1e3", 0, 0, ErrNotInteger},
+		{"0x7fffffffffffffff", AllowJSON5, math.MaxInt64, nil},
+		{"-0x8000000000000000", AllowJSON5, math.MinInt64, nil},
+		{"0x10000000000000000", AllowJSON5, 0, ErrOverflow},
+		{"+12", AllowJSON5, 12, nil},
+		{"Infinity", AllowJSON5, 0, ErrNotInteger},
+	} {
+		info := numinfo(t, tc.token, tc.opts)
+		x, err := ParseInt64([]byte(tc.token), info)
+		if x != tc.expect || err != tc.err {
+			t.Fatalf("%s: expected %d %v, got %d %v", tc.token, tc.expect,
+				tc.err, x, err)
+		}
+	}
+}
+
+func TestParseUint64(t *testing.T) {
+	for _, tc := range []struct {
+		token  string
+		opts   int
+		expect uint64
+		err    error
+	}{
+		{"0", 0, 0, nil},
+		{"-0", 0, 0, nil},
+		{"-1", 0, 0, ErrOverflow},
+		{"18446744073709551615", 0, math.MaxUint64, nil},
+		{"18446744073709551616", 0, 0, ErrOverflow},
+		{"99999999999999999999", 0, 0, ErrOverflow},
+		{"0xFFFFFFFFFFFFFFFF", AllowJSON5, math.MaxUint64, nil},
+		{"1.5", 0, 0, ErrNotInteger},
+	} {
+		info := numinfo(t, tc.token, tc.opts)
+		x, err := ParseUint64([]byte(tc.token), info)
+		if x != tc.expect || err != tc.err {
+			t.Fatalf("%s: expected %d %v, got %d %v", tc.token, tc.expect,
+				tc.err, x, err)
+		}
+	}
+	if _, err := ParseUint64([]byte("12a"), Number); err != ErrInvalid {
+		t.Fatalf("expected %v, got %v", ErrInvalid, err)
+	}
+}
+
+func testfloat(t *testing.T, token string, opts int) {
+	t.Helper()
+	info := numinfo(t, token, opts)
+	f, err := ParseFloat64([]byte(token), info)
+	expect, experr := strconv.ParseFloat(token, 64)
+	if info&Hex == Hex {
+		expect, experr = strconv.ParseFloat(token+"p0", 64)
+	}
+	if experr != nil {
+		if err != ErrOverflow || f != expect {
+			t.Fatalf("%s: expected %v %v, got %v %v", token, expect,
+				ErrOverflow, f, err)
+		}
+		return
+	}
+	if err != nil || math.Float64bits(f) != math.Float64bits(expect) {
+		t.Fatalf("%s: expected %v, got %v %v", token, expect, f, err)
+	}
+}
+
+func TestParseFloat64(t *testing.T) {
+	for _, token := range []string{
+		"0", "-0", "0.0", "-0.0", "1", "-1", "123456789012345",
+		"1234567890123456", "12345678901234567890123", "0.1", "0.3",
+		"-0.000123", "1e22", "1e23", "1.5e-22", "1e-23", "1.7976931348623157e308",
+		"1.8e308", "-1.8e308", "4.9e-324", "2e-324", "1e-400", "1e100000000",
+		"123456789.123456789e-5", "9007199254740993", "9007199254740993.0",
+		"0.00000000000000000000000000000000000001",
+		"89255.0e-22", "2.2250738585072011e-308", "2.2250738585072012e-308",
+	} {
+		testfloat(t, token, 0)
+	}
+	for _, token := range []string{".5", "5.", "+1.5", "-.5e2", "0x1F",
+		"0xFFFFFFFFFFFFFFFFFFFF", "-0x1fffffffffffff",
+	} {
+		testfloat(t, token, AllowJSON5)
+	}
+	for _, token := range []string{"Infinity", "-Infinity", "NaN"} {
+		info := numinfo(t, token, AllowJSON5)
+		f, err := ParseFloat64([]byte(token), info)
+		expect, _ := strconv.ParseFloat(token, 64)
+		if err != nil || !(f == expect || math.IsNaN(f) && math.IsNaN(expect)) {
+			t.Fatalf("%s: expected %v, got %v %v", token, expect, f, err)
+		}
+	}
+	rand.Seed(time.Now().UnixNano())
+	for i := 0; i < 100000; i++ {
+		var token []byte
+		switch rand.Intn(4) {
+		case 0:
+			token = strconv.AppendFloat(nil, rand.NormFloat64(), 'f', -1, 64)
+		case 1:
+			token = strconv.AppendFloat(nil, rand.ExpFloat64(), 'e',
+				rand.Intn(20), 64)
+		case 2:
+			token = strconv.AppendFloat(nil,
+				math.Float64frombits(rand.Uint64()&^(0x7FF<<52)|
+					uint64(rand.Intn(0x7FF))<<52), 'g', -1, 64)
+		case 3:
+			token = strconv.AppendInt(nil, rand.Int63()>>rand.Intn(63), 10)
+		}
+		testfloat(t, string(token), 0)
+	}
+}
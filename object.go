@@ -0,0 +1,61 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import "errors"
+
+// ErrNotObject is returned by ParseObject when the document is not an Object.
+var ErrNotObject = errors.New("pjson: not an object")
+
+// ParseObject parses a JSON Object and calls 'fn' for each of its members.
+// The 'keyStart' and 'keyEnd' params are the start and end indexes of the
+// key, and 'valStart' and 'valEnd' are the indexes of the entire value,
+// such that json[valStart:valEnd] is a complete String, Number, Object, etc.
+// The 'info' param is the info of the value, which for Objects and Arrays
+// only has the Object or Array bit, and the Value bit.
+// Returning 0 from 'fn' will stop the parsing, otherwise the parsing
+// continues with the next member. The children of the member values are
+// skipped, like returning -1 from the 'iter' function of Parse, but they are
+// still validated.
+// The return values are the same as ParseErr, or ErrNotObject when the
+// document is valid but it's not an Object.
+func ParseObject[T Input](json T,
+	fn func(keyStart, keyEnd, valStart, valEnd, info int) int,
+) (int, error) {
+	var keyStart, keyEnd, mark int
+	var notobj bool
+	n, err := ParseErr(json, 0, func(start, end, info int) int {
+		switch {
+		case notobj:
+			return 1
+		case info&Start == Start:
+			if info&(Object|Open) != Object|Open {
+				// Validate the rest of the document, for the error.
+				notobj = true
+				if info&Open == Open {
+					return -1
+				}
+			}
+		case info&Key == Key:
+			keyStart, keyEnd = start, end
+		case info&(Value|Open) == Value|Open:
+			mark = start
+			return -1
+		case info&(Value|Close) == Value|Close:
+			start = mark
+			info &^= Close
+			fallthrough
+		case info&Value == Value:
+			if fn(keyStart, keyEnd, start, end, info) == 0 {
+				return 0
+			}
+		}
+		return 1
+	})
+	if notobj && err == nil {
+		return 0, ErrNotObject
+	}
+	return n, err
+}
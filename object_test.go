@@ -0,0 +1,56 @@
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParseObject(t *testing.T) {
+	json := []byte(`{"a":1, "b" : {"c":[2,3]}, "d":[{}], "e":"\"x\"", "f":null}`)
+	var out []string
+	n, err := ParseObject(json, func(keyStart, keyEnd, valStart, valEnd,
+		info int) int {
+		out = append(out, fmt.Sprintf("%s=%s:%d", json[keyStart:keyEnd],
+			json[valStart:valEnd], info))
+		return 1
+	})
+	expect := fmt.Sprint([]string{
+		fmt.Sprintf(`"a"=1:%d`, Value|Number),
+		fmt.Sprintf(`"b"={"c":[2,3]}:%d`, Value|Object),
+		fmt.Sprintf(`"d"=[{}]:%d`, Value|Array),
+		fmt.Sprintf(`"e"="\"x\"":%d`, Value|String|Escaped),
+		fmt.Sprintf(`"f"=null:%d`, Value|Null),
+	})
+	if n != len(json) || err != nil || fmt.Sprint(out) != expect {
+		t.Fatalf("expected %s, got %v %d %v", expect, out, n, err)
+	}
+
+	// Stop at the second member.
+	out = out[:0]
+	n, err = ParseObject(json, func(keyStart, keyEnd, valStart, valEnd,
+		info int) int {
+		out = append(out, string(json[keyStart:keyEnd]))
+		return len(out) - 2
+	})
+	if n != 25 || err != nil || fmt.Sprint(out) != `["a" "b"]` {
+		t.Fatalf("expected 25, got %v %d %v", out, n, err)
+	}
+
+	fn := func(keyStart, keyEnd, valStart, valEnd, info int) int { return 1 }
+	for _, json := range []string{`[1]`, `1`, `"a"`} {
+		if _, err := ParseObject([]byte(json), fn); err != ErrNotObject {
+			t.Fatalf("%q: expected %v, got %v", json, ErrNotObject, err)
+		}
+	}
+	for _, json := range []string{`{"a":[1,}`, `[1,`, `1 x`, `[[1],]`} {
+		_, err := ParseObject([]byte(json), fn)
+		var serr *SyntaxError
+		if !errors.As(err, &serr) {
+			t.Fatalf("%q: expected a syntax error, got %v", json, err)
+		}
+	}
+	if n, err := ParseObject([]byte(` {} `), fn); n != 4 || err != nil {
+		t.Fatalf("expected 4, got %d %v", n, err)
+	}
+}
@@ -27,8 +27,56 @@ const (
 	Sign    // token is a signed Number (has a '-' prefix)
 	Dot     // token is a Number that has a dot (radix point)
 	E       // token is a Number in scientific notation (has 'E' or 'e')
+
+	Ident       // token is an unquoted Object key (JSON5)
+	SingleQuote // token is a String in single quotes (JSON5)
+	Hex         // token is a hexadecimal Number (JSON5)
+	Infinity    // token is an Infinity Number (JSON5)
+	NaN         // token is a NaN Number (JSON5)
+
+	Comment       // token is a comment (see ReportComments)
+	NonASCII      // token is a String with non-ASCII characters (see ValidateUTF8)
+	LoneSurrogate // token is a String with a lone UTF-16 surrogate escape
+	Duplicate     // token is a repeated Object key (see ReportDuplicates)
+)
+
+// Bit flags for the "opts" parameter of Parse.
+const (
+	// AllowJSON5 allows for JSON5 documents, which may have single-quoted
+	// strings, unquoted keys, trailing commas, hexadecimal numbers, numbers
+	// with a leading or trailing decimal point or a '+' prefix, Infinity,
+	// NaN, and comments. See https://spec.json5.org
+	AllowJSON5 = 1 << iota
+	// AllowComments allows for "//" line comments and "/* */" block comments
+	// wherever whitespace is allowed.
+	AllowComments
+	// ReportComments allows for comments, like AllowComments, and it also
+	// passes each comment to the 'iter' function with the Comment info bit.
+	ReportComments
+	// ValidateUTF8 makes invalid UTF-8 in Strings and keys an error, and it
+	// sets the NonASCII info bit for Strings and keys that have multibyte
+	// characters.
+	ValidateUTF8
+	// RejectSurrogates makes a \u escape in a String or key that is an
+	// unpaired UTF-16 surrogate an error, such as "\ud800", or "\udc00\ud800"
+	// which is reversed. Without this option, such Strings are reported with
+	// the LoneSurrogate info bit.
+	RejectSurrogates
+	// ReportDuplicates sets the Duplicate info bit for an Object key that is
+	// equal to a prior key in the same Object. Keys are compared by their
+	// unescaped values, so "a" and "\u0061" are equal.
+	ReportDuplicates
+	// RejectDuplicates makes a duplicate Object key an error, which is at
+	// the second key. See ReportDuplicates.
+	RejectDuplicates
 )
 
+// Input is the type of a JSON document, which is either a byte slice or a
+// string. A string document is parsed in place, without being copied.
+type Input interface {
+	~[]byte | ~string
+}
+
 // Parse JSON.
 // The iter function is a callback that fires for every element in the JSON
 // document. Elements include all values and tokens.
@@ -36,6 +84,8 @@ const (
 // respective element, such that json[start:end] will equal the complete
 // element data.
 // The 'info' param provides extra information about the element data.
+// The 'opts' param is zero for strict JSON, or a combination of option flags,
+// such as AllowJSON5 and AllowComments.
 // Returning 0 from 'iter' will stop the parsing.
 // Returning 1 from 'iter' will continue the parsing.
 // Returning -1 from 'iter' will skip all children elements in the current
@@ -43,7 +93,10 @@ const (
 // has the Open bit set, otherwise it effectively works like returning 1.
 // This operation returns zero or a negative value if an error occured. This
 // value represents the position that the parser was at when it discovered the
-// error. To get the true offset multiple this value by -1.
+// error. To get the true offset multiple this value by -1. Use ParseErr for
+// more details about the error.
+// A document with Objects and Arrays that are nested deeper than
+// DefaultMaxDepth is an error. Use ParseLimits for a different maximum.
 //
 //	e := Parse(json, iter)
 //	if e < 0 {
@@ -55,8 +108,13 @@ const (
 // stopped early then this value will be the position the parser was at when it
 // stopped, otherwise the value will be equal the length of the original json
 // document.
-func Parse(json []byte, opts int, iter func(start, end, info int) int) int {
-	i, ok, _ := vdoc(json, 0, iter)
+func Parse[T Input](json T, opts int, iter func(start, end, info int) int) int {
+	if opts != 0 {
+		var s stream[T]
+		s.iter = iter
+		return s.parse(json, opts)
+	}
+	i, ok, _ := vdoc(json, 0, iter, DefaultMaxDepth)
 	if !ok {
 		i *= -1
 	}
@@ -71,8 +129,10 @@ func isws(ch byte) bool {
 
 type vfn func(start, end, info int) int
 
-func vdoc(json []byte, i int, f vfn) (oi int, ok, stop bool) {
-	i, ok, stop = vany(json, i, Start, f)
+// vdoc validates a document. The 'depth' is the maximum nesting depth of the
+// Objects and Arrays, which guards against exhausting the call stack.
+func vdoc[T Input](json T, i int, f vfn, depth int) (oi int, ok, stop bool) {
+	i, ok, stop = vany(json, i, Start, f, depth)
 	if stop {
 		return i, ok, stop
 	}
@@ -93,54 +153,62 @@ var strtoks = [256]byte{
 	'"': 1, '\\': 1,
 }
 
-func isstrtok(ch byte) bool {
-	return strtoks[ch] == 1
-}
+// strtoks8 is strtoks along with all non-ASCII bytes, for ValidateUTF8.
+var strtoks8 = func() [256]byte {
+	toks := strtoks
+	for i := 0x80; i < 256; i++ {
+		toks[i] = 1
+	}
+	return toks
+}()
 
 const unroll = true
 
-// validstring - the prefix '"' character has already been processed
-func vstring(json []byte, i int) (outi, info int, ok, stop bool) {
+// validstring - the prefix '"' character has already been processed. The
+// 'toks' are the bytes that need inspection, which is strtoks or strtoks8.
+func vstring[T Input](json T, i int, toks *[256]byte) (outi, info int, ok,
+	stop bool,
+) {
 	for {
 		if unroll {
 			for i < len(json)-7 {
 				jsonv := json[i : i+8]
-				if isstrtok(jsonv[0]) {
+				if toks[jsonv[0]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[1]) {
+				if toks[jsonv[1]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[2]) {
+				if toks[jsonv[2]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[3]) {
+				if toks[jsonv[3]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[4]) {
+				if toks[jsonv[4]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[5]) {
+				if toks[jsonv[5]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[6]) {
+				if toks[jsonv[6]] == 1 {
 					goto tok
 				}
 				i++
-				if isstrtok(jsonv[7]) {
+				if toks[jsonv[7]] == 1 {
 					goto tok
 				}
 				i++
 			}
 		}
 		for ; i < len(json); i++ {
-			if isstrtok(json[i]) {
+			if toks[json[i]] == 1 {
 				goto tok
 			}
 		}
@@ -174,14 +242,85 @@ func vstring(json []byte, i int) (outi, info int, ok, stop bool) {
 						return i, info, false, true
 					}
 				}
+				if json[i-3]|0x20 == 'd' {
+					var paired bool
+					if i, paired = vsurrogate(json, i); !paired {
+						info |= LoneSurrogate
+					}
+				}
 			}
+		} else {
+			// non-ASCII, only when validating UTF-8
+			info |= NonASCII
+			if i, ok = vutf8(json, i); !ok {
+				return i, info, false, true
+			}
+			continue
 		}
 		i++
 	}
 	return i, info, false, true
 }
 
-func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
+// vsurrogate checks the \u escape that ends at json[i], which may be a UTF-16
+// surrogate. A high surrogate must be followed by a low surrogate escape, in
+// which case the position of the last hex digit of the low surrogate is
+// returned. It returns false for a lone or reversed surrogate.
+func vsurrogate[T Input](json T, i int) (outi int, paired bool) {
+	r, _ := unhex(json[i-3:], 4)
+	if r < 0xD800 || r >= 0xE000 {
+		return i, true
+	}
+	if r < 0xDC00 && i+6 < len(json) && json[i+1] == '\\' &&
+		json[i+2] == 'u' {
+		r2, ok := unhex(json[i+3:], 4)
+		if ok && r2 >= 0xDC00 && r2 < 0xE000 {
+			return i + 6, true
+		}
+	}
+	return i, false
+}
+
+// vutf8 returns the end of the UTF-8 character at json[i], or false if the
+// character is invalid. An incomplete character at the end of json is
+// invalid at len(json).
+func vutf8[T Input](json T, i int) (outi int, ok bool) {
+	// The ranges of the second byte exclude overlong encodings, surrogates
+	// and characters above U+10FFFF, like utf8.DecodeRune.
+	n, lo, hi := 0, byte(0x80), byte(0xBF)
+	switch c := json[i]; {
+	case c >= 0xC2 && c <= 0xDF:
+		n = 2
+	case c == 0xE0:
+		n, lo = 3, 0xA0
+	case c == 0xED:
+		n, hi = 3, 0x9F
+	case c >= 0xE1 && c <= 0xEF:
+		n = 3
+	case c == 0xF0:
+		n, lo = 4, 0x90
+	case c == 0xF4:
+		n, hi = 4, 0x8F
+	case c >= 0xF1 && c <= 0xF3:
+		n = 4
+	default:
+		return i, false
+	}
+	for j := i + 1; j < i+n; j++ {
+		if j == len(json) {
+			return len(json), false
+		}
+		if json[j] < lo || json[j] > hi {
+			return i, false
+		}
+		lo, hi = 0x80, 0xBF
+	}
+	return i + n, true
+}
+
+func vany[T Input](json T, i int, dinfo int, f vfn, depth int) (oi int, ok,
+	stop bool,
+) {
 	for ; i < len(json); i++ {
 		if isws(json[i]) {
 			continue
@@ -189,9 +328,12 @@ func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
 		mark := i
 		var info int
 		if json[i] == '"' {
-			i, info, ok, stop = vstring(json, i+1)
+			i, info, ok, stop = vstring(json, i+1, &strtoks)
 			info |= String
 		} else if json[i] == '{' {
+			if depth == 0 {
+				return i, false, true
+			}
 			f2 := f
 			if f != nil {
 				r := f(i, i+1, Object|Open|dinfo)
@@ -202,7 +344,7 @@ func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
 					f2 = nil
 				}
 			}
-			i, ok, stop = vobject(json, i+1, f2)
+			i, ok, stop = vobject(json, i+1, f2, depth-1)
 			if stop {
 				return i, ok, stop
 			}
@@ -217,6 +359,9 @@ func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
 			}
 			return i, true, false
 		} else if json[i] == '[' {
+			if depth == 0 {
+				return i, false, true
+			}
 			f2 := f
 			if f != nil {
 				r := f(i, i+1, Array|Open|dinfo)
@@ -227,7 +372,7 @@ func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
 					f2 = nil
 				}
 			}
-			i, ok, stop = varray(json, i+1, f2)
+			i, ok, stop = varray(json, i+1, f2, depth-1)
 			if stop {
 				return i, ok, stop
 			}
@@ -272,7 +417,7 @@ func vany(json []byte, i int, dinfo int, f vfn) (oi int, ok, stop bool) {
 	return i, false, true
 }
 
-func vobject(json []byte, i int, f vfn) (oi int, ok, stop bool) {
+func vobject[T Input](json T, i int, f vfn, depth int) (oi int, ok, stop bool) {
 	for ; i < len(json); i++ {
 		if isws(json[i]) {
 			continue
@@ -284,7 +429,7 @@ func vobject(json []byte, i int, f vfn) (oi int, ok, stop bool) {
 		key:
 			mark := i
 			var info int
-			i, info, ok, stop = vstring(json, i+1)
+			i, info, ok, stop = vstring(json, i+1, &strtoks)
 			if stop {
 				return i, ok, stop
 			}
@@ -301,7 +446,7 @@ func vobject(json []byte, i int, f vfn) (oi int, ok, stop bool) {
 					return i, true, true
 				}
 			}
-			if i, ok, stop = vany(json, i, Value, f); stop {
+			if i, ok, stop = vany(json, i, Value, f, depth); stop {
 				return i, ok, stop
 			}
 			if i, ok, stop = vcomma(json, i, '}'); stop {
@@ -332,7 +477,7 @@ func vobject(json []byte, i int, f vfn) (oi int, ok, stop bool) {
 	return i, false, true
 }
 
-func varray(json []byte, i int, f vfn) (oi int, ok, stop bool) {
+func varray[T Input](json T, i int, f vfn, depth int) (oi int, ok, stop bool) {
 	for ; i < len(json); i++ {
 		if isws(json[i]) {
 			continue
@@ -344,7 +489,7 @@ func varray(json []byte, i int, f vfn) (oi int, ok, stop bool) {
 			if isws(json[i]) {
 				continue
 			}
-			if i, ok, stop = vany(json, i, Value, f); stop {
+			if i, ok, stop = vany(json, i, Value, f, depth); stop {
 				return i, ok, stop
 			}
 			if i, ok, stop = vcomma(json, i, ']'); stop {
@@ -359,11 +504,12 @@ func varray(json []byte, i int, f vfn) (oi int, ok, stop bool) {
 				}
 			}
 		}
+		break
 	}
 	return i, false, true
 }
 
-func vcolon(json []byte, i int) (outi int, ok, stop bool) {
+func vcolon[T Input](json T, i int) (outi int, ok, stop bool) {
 loop:
 	if i < len(json) {
 		if json[i] == ':' {
@@ -377,7 +523,7 @@ loop:
 	return i, false, true
 }
 
-func vcomma(json []byte, i int, end byte) (outi int, ok, stop bool) {
+func vcomma[T Input](json T, i int, end byte) (outi int, ok, stop bool) {
 loop:
 	if i < len(json) {
 		if json[i] == ',' {
@@ -398,7 +544,7 @@ func isnum(ch byte) bool {
 	return ch >= '0' && ch <= '9'
 }
 
-func vnumber(json []byte, i int) (outi, info int, ok, stop bool) {
+func vnumber[T Input](json T, i int) (outi, info int, ok, stop bool) {
 	i-- // go back one byte
 
 	if json[i] == '-' {
@@ -468,7 +614,7 @@ exp:
 	return i, info, true, false
 }
 
-func vtrue(json []byte, i int) (outi int, ok, stop bool) {
+func vtrue[T Input](json T, i int) (outi int, ok, stop bool) {
 	if i+3 <= len(json) && (uint32(json[i])|
 		uint32(json[i+1])<<8|
 		uint32(json[i+2])<<16) == 6649202 {
@@ -477,7 +623,7 @@ func vtrue(json []byte, i int) (outi int, ok, stop bool) {
 	return i, false, true
 }
 
-func vfalse(json []byte, i int) (outi int, ok, stop bool) {
+func vfalse[T Input](json T, i int) (outi int, ok, stop bool) {
 	if i+4 <= len(json) && uint32(json[i])|
 		uint32(json[i+1])<<8|
 		uint32(json[i+2])<<16|
@@ -487,7 +633,7 @@ func vfalse(json []byte, i int) (outi int, ok, stop bool) {
 	return i, false, true
 }
 
-func vnull(json []byte, i int) (outi int, ok, stop bool) {
+func vnull[T Input](json T, i int) (outi int, ok, stop bool) {
 	if i+3 <= len(json) && uint32(json[i])|
 		uint32(json[i+1])<<8|
 		uint32(json[i+2])<<16 == 7105653 {
@@ -495,3 +641,36 @@ func vnull(json []byte, i int) (outi int, ok, stop bool) {
 	}
 	return i, false, true
 }
+
+// litfail returns the position of the first byte that does not match the
+// rest of a literal.
+func litfail[T Input](json T, i int, lit string) int {
+	for j := 0; j < len(lit) && i < len(json) && json[i] == lit[j]; j++ {
+		i++
+	}
+	return i
+}
+
+// vcomment - the prefix '/' character has already been processed
+func vcomment[T Input](json T, i int) (outi int, ok bool) {
+	if i == len(json) {
+		return i, false
+	}
+	switch json[i] {
+	case '/':
+		for i++; i < len(json); i++ {
+			if json[i] == '\n' || json[i] == '\r' {
+				break
+			}
+		}
+		return i, true
+	case '*':
+		for i++; i < len(json)-1; i++ {
+			if json[i] == '*' && json[i+1] == '/' {
+				return i + 2, true
+			}
+		}
+		return len(json), false
+	}
+	return i, false
+}
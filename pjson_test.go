@@ -3,7 +3,9 @@ package pjson
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"math/rand"
 	"os"
@@ -336,6 +338,12 @@ func TestReturnValues(t *testing.T) {
 	testreturnvalue(t, " ", -1)
 	testreturnvalue(t, " a", -1)
 	testreturnvalue(t, ` {"hel\y" : 1}`, -7)
+	testreturnvalue(t, `[1,`, -3)
+	testreturnvalue(t, `[1, `, -4)
+	testreturnvalue(t, `[trux]`, -2)
+	testreturnvalue(t, `[tru`, -2)
+	testreturnvalue(t, ` fals0`, -2)
+	testreturnvalue(t, `{"a":nul}`, -6)
 }
 
 func testvalid(t *testing.T, json string, expect bool) {
@@ -433,6 +441,194 @@ func TestValidBasic(t *testing.T) {
 	testvalid(t, `123.123e`, false)
 }
 
+func testutf8(t *testing.T, json string, opts int, kind ErrorKind,
+	offset int,
+) {
+	t.Helper()
+	n, err := ParseErr([]byte(json), opts|ValidateUTF8, nil)
+	if kind == 0 {
+		if err != nil || n != len(json) {
+			t.Fatalf("%q: expected %d, got %d %v", json, len(json), n, err)
+		}
+		return
+	}
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Kind != kind || serr.Offset != offset {
+		t.Fatalf("%q: expected %v at %d, got %v", json, kind, offset, err)
+	}
+	if n, err := ParseErr([]byte(json), opts, nil); err != nil && kind ==
+		InvalidUTF8 {
+		t.Fatalf("%q: expected valid without ValidateUTF8, got %d %v", json,
+			n, err)
+	}
+}
+
+func TestValidateUTF8(t *testing.T) {
+	testutf8(t, `"hello"`, 0, 0, 0)
+	testutf8(t, `"h\u00e9llo wörld 世界 😀"`, 0, 0, 0)
+	testutf8(t, "\"\xef\xbf\xbd\"", 0, 0, 0)
+	testutf8(t, "\"a\xffb\"", 0, InvalidUTF8, 2)
+	testutf8(t, "\"\xc3\x28\"", 0, InvalidUTF8, 1)
+	testutf8(t, "\"\xc0\xaf\"", 0, InvalidUTF8, 1)
+	testutf8(t, "\"\xed\xa0\x80\"", 0, InvalidUTF8, 1)
+	testutf8(t, "\"\xf4\x90\x80\x80\"", 0, InvalidUTF8, 1)
+	testutf8(t, "\"\x80\"", 0, InvalidUTF8, 1)
+	testutf8(t, "[\"abcdefghijklmnop\xe2\x82\"]", 0, InvalidUTF8, 18)
+	testutf8(t, "{\"abcdefgh\xe2\x82\xac\":\"ok\",\"\xfe\":1}", 0,
+		InvalidUTF8, 21)
+	testutf8(t, "\"abc\xe2\x82", 0, UnterminatedString, 6)
+	testutf8(t, "{\u043a\u043b\u044e\u0447:'\xe5\x80\xbc'}", AllowJSON5, 0, 0)
+	testutf8(t, "{'a':'b\xff'}", AllowJSON5, InvalidUTF8, 7)
+	testutf8(t, "{a\xff:1}", AllowJSON5, InvalidUTF8, 2)
+	testutf8(t, "{\xff:1}", AllowJSON5, InvalidUTF8, 1)
+
+	json := []byte(`{"ключ":"value","key":"値😀",ident:"a"}`)
+	var out []string
+	Parse(json, AllowJSON5|ValidateUTF8, func(start, end, info int) int {
+		if info&(Key|String) != 0 {
+			out = append(out, fmt.Sprintf("%s:%t", json[start:end],
+				info&NonASCII != 0))
+		}
+		return 1
+	})
+	expect := `["ключ":true "value":false "key":false "値😀":true ` +
+		`ident:false "a":false]`
+	if fmt.Sprint(out) != expect {
+		t.Fatalf("expected %v, got %v", expect, out)
+	}
+
+	// The chunked parser must wait for the rest of a split character.
+	var evs []string
+	p := NewParser(ValidateUTF8, func(start, end, info int, tok []byte) int {
+		evs = append(evs, string(tok))
+		return 1
+	})
+	json = []byte(`["😀😀","世界"]`)
+	for i := 0; i < len(json); i++ {
+		if _, err := p.Write(json[i : i+1]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(evs) != `[[ "😀😀" , "世界" ]]` {
+		t.Fatalf("got %v", evs)
+	}
+
+	_, err := ParseErr([]byte("\"\xff\""), ValidateUTF8, nil)
+	if err == nil || err.Error() !=
+		`pjson: invalid UTF-8 '\xff' at line 1, column 2 (offset 1)` {
+		t.Fatalf("got %v", err)
+	}
+}
+
+func testsurrogate(t *testing.T, json string, opts int, offset int) {
+	t.Helper()
+	var info int
+	Parse([]byte(json), opts, func(start, end, i int) int {
+		info |= i
+		return 1
+	})
+	if (info&LoneSurrogate != 0) != (offset >= 0) {
+		t.Fatalf("%q: expected lone surrogate %t", json, offset >= 0)
+	}
+	n, err := ParseErr([]byte(json), opts|RejectSurrogates, nil)
+	var serr *SyntaxError
+	if offset < 0 {
+		if err != nil || n != len(json) {
+			t.Fatalf("%q: expected %d, got %d %v", json, len(json), n, err)
+		}
+	} else if !errors.As(err, &serr) || serr.Kind != BadSurrogate ||
+		serr.Offset != offset {
+		t.Fatalf("%q: expected %v at %d, got %v", json, BadSurrogate, offset,
+			err)
+	}
+	p := NewParser(opts|RejectSurrogates, nil)
+	for i := 0; i < len(json); i++ {
+		p.Write([]byte(json[i : i+1]))
+	}
+	if err2 := p.Close(); fmt.Sprint(err2) != fmt.Sprint(err) {
+		t.Fatalf("%q: expected %v, got %v", json, err, err2)
+	}
+}
+
+func TestSurrogates(t *testing.T) {
+	testsurrogate(t, `"\ud83d\ude00"`, 0, -1)
+	testsurrogate(t, `"\uD83D\uDE00\uD7FF\uE000"`, 0, -1)
+	testsurrogate(t, `"\ud800"`, 0, 1)
+	testsurrogate(t, `"\udc00\ud800"`, 0, 1)
+	testsurrogate(t, `"\ud800\u0041"`, 0, 1)
+	testsurrogate(t, `"\ud800\\udc00"`, 0, 1)
+	testsurrogate(t, `"\ud83d\ud83d\ude00"`, 0, 1)
+	testsurrogate(t, `["ok","a\ud800"]`, 0, 8)
+	testsurrogate(t, `{"\ud800\udc00x\udfff":1}`, 0, 15)
+	testsurrogate(t, `{'\ud800':1}`, AllowJSON5, 2)
+	testsurrogate(t, `{a:'\x41\udbff\udfff'}`, AllowJSON5, -1)
+	testsurrogate(t, `{a:'\udbff\x41'}`, AllowJSON5, 4)
+}
+
+func testduplicates(t *testing.T, json string, opts int, skip bool,
+	expect string, offset int,
+) {
+	t.Helper()
+	var dups []string
+	iter := func(start, end, info int) int {
+		if info&Duplicate != 0 {
+			dups = append(dups, json[start:end])
+		}
+		if skip && info&Open != 0 && info&Start == 0 {
+			return -1
+		}
+		return 1
+	}
+	n, err := ParseErr([]byte(json), opts|ReportDuplicates, iter)
+	if err != nil || n != len(json) || fmt.Sprint(dups) != expect {
+		t.Fatalf("%q: expected %v, got %v %d %v", json, expect, dups, n, err)
+	}
+	n, err = ParseErr([]byte(json), opts|RejectDuplicates, iter)
+	var serr *SyntaxError
+	if offset < 0 {
+		if err != nil || n != len(json) {
+			t.Fatalf("%q: expected %d, got %d %v", json, len(json), n, err)
+		}
+	} else if !errors.As(err, &serr) || serr.Kind != DuplicateKey ||
+		serr.Offset != offset {
+		t.Fatalf("%q: expected %v at %d, got %v", json, DuplicateKey, offset,
+			err)
+	}
+	p := NewParser(opts|RejectDuplicates, nil)
+	for i := 0; i < len(json); i++ {
+		p.Write([]byte(json[i : i+1]))
+	}
+	if err2 := p.Close(); fmt.Sprint(err2) != fmt.Sprint(err) {
+		t.Fatalf("%q: expected %v, got %v", json, err, err2)
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	testduplicates(t, `{"a":1,"b":2}`, 0, false, `[]`, -1)
+	testduplicates(t, `{"a":1,"a":2}`, 0, false, `["a"]`, 7)
+	testduplicates(t, `{"a":1,"\u0061":2,"a":3}`, 0, false,
+		`["\u0061" "a"]`, 7)
+	testduplicates(t, `{"a\/b":1,"a/b":2}`, 0, false, `["a/b"]`, 10)
+	testduplicates(t, `{"a":{"a":1},"b":[{"a":1},{"a":2}]}`, 0, false,
+		`[]`, -1)
+	testduplicates(t, `[{"a":1,"b":{"a":2}},{"b":1,"a":2}]`, 0, false,
+		`[]`, -1)
+	testduplicates(t, `{"a":{"b":1,"b":2}}`, 0, false, `["b"]`, 12)
+	testduplicates(t, `{"x":{"a":1,"a":2},"y":[{"x":0}],"x":3}`, 0, true,
+		`["x"]`, 12)
+	testduplicates(t, `{"x":{"a":1},"y":[{"x":0,"x":1}],"x":3}`, 0, true,
+		`["x"]`, 25)
+	testduplicates(t, `{"\ud800":1,"\udbff":2}`, 0, false, `[]`, -1)
+	testduplicates(t, `{"\ud800":1,"\ud800":2}`, 0, false,
+		`["\ud800"]`, 12)
+	testduplicates(t, `{a:1,'a':2,"a":3,}`, AllowJSON5, false,
+		`['a' "a"]`, 5)
+	testduplicates(t, `{a:1,/*"a":2*/b:2}`, AllowJSON5, false, `[]`, -1)
+}
+
 // mustBeGood parses JSON and stitches together a new JSON document and checks
 // if the new doc matches the original.
 func mustBeAGood(json []byte) {
@@ -470,6 +666,70 @@ func TestFiles(t *testing.T) {
 	}
 }
 
+// stringEvents returns the elements and the error for a document, using the
+// entry points that accept any Input.
+func stringEvents[T Input](json T, opts int) string {
+	var out []string
+	n, err := ParseErr(json, opts, func(start, end, info int) int {
+		out = append(out, fmt.Sprintf("%d:%d:%d:%s", start, end, info,
+			json[start:end]))
+		return 1
+	})
+	out = append(out, fmt.Sprint(n, err, Parse(json, opts, nil)))
+	ParseTokens(json, opts, func(tok Token) int {
+		out = append(out, fmt.Sprint(tok))
+		return 1
+	})
+	tz := NewTokenizer(json, opts)
+	for {
+		tok, err := tz.Next()
+		out = append(out, fmt.Sprint(tok, err))
+		if err != nil {
+			break
+		}
+	}
+	n, err = ParseObject(json, func(ks, ke, vs, ve, info int) int {
+		key, _ := Unescape(json[ks:ke])
+		out = append(out, key)
+		if info&Number == Number {
+			f, _ := ParseFloat64(json[vs:ve], info)
+			out = append(out, fmt.Sprint(f))
+		}
+		return 1
+	})
+	out = append(out, fmt.Sprint(n, err, ValidOpts(json, opts)))
+	return strings.Join(out, "\n")
+}
+
+func TestStringInput(t *testing.T) {
+	docs := []string{
+		json1, json2, `{"a\u0062":"\ud83d\ude00","c":[1,-2.5e3,true]}`,
+		`{"a":1,"a":2}`, "{\"\xff\":1}", `[1,2`, `{a:0x1F,'b':Infinity}`,
+		"/* c */ [1]",
+	}
+	for _, opts := range []int{0, AllowJSON5 | ReportComments | ValidateUTF8 |
+		ReportDuplicates} {
+		for _, json := range docs {
+			s1 := stringEvents(json, opts)
+			s2 := stringEvents([]byte(json), opts)
+			if s1 != s2 {
+				t.Fatalf("%q: expected\n%s\ngot\n%s", json, s2, s1)
+			}
+		}
+	}
+	if !ValidString(json1) || ValidString(`{"a"}`) {
+		t.Fatal("ValidString")
+	}
+	allocs := testing.AllocsPerRun(10, func() {
+		Parse(json1, 0, nil)
+		Valid(json1)
+		ParseHandler(json1, 0, BaseHandler{})
+	})
+	if allocs != 0 {
+		t.Fatalf("expected 0 allocs, got %v", allocs)
+	}
+}
+
 // lotsaOps preforms lots of operations and prints the results.
 func lotsaOps(tag string, N int, op func() int) {
 	start := time.Now()
@@ -514,6 +774,31 @@ func testSpeed(path string) {
 		}
 		return len(jdata)
 	})
+	sdata := string(jdata)
+	lotsaOps("pjson.Parse (string)", N, func() int {
+		if Parse(sdata, 0, nil) < 0 {
+			panic("invalid")
+		}
+		return len(sdata)
+	})
+	lotsaOps("pjson.Valid", N, func() int {
+		if !Valid(jdata) {
+			panic("invalid")
+		}
+		return len(jdata)
+	})
+	lotsaOps("pjson.Tokenizer", N, func() int {
+		t := NewTokenizer(jdata, 0)
+		for {
+			if _, err := t.Next(); err != nil {
+				if err != io.EOF {
+					panic(err)
+				}
+				break
+			}
+		}
+		return len(jdata)
+	})
 	lotsaOps("json.Valid (stdlib)", N, func() int {
 		if !json.Valid(jdata) {
 			panic("invalid")
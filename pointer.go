@@ -0,0 +1,163 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"errors"
+	"strconv"
+)
+
+var (
+	// ErrInvalidPointer is returned by ResolvePointer when the pointer is not
+	// a valid JSON Pointer.
+	ErrInvalidPointer = errors.New("pjson: invalid pointer")
+	// ErrNotFound is returned by ResolvePointer when the pointer does not
+	// refer to a value in the document.
+	ErrNotFound = errors.New("pjson: not found")
+)
+
+// ResolvePointer returns the value that a JSON Pointer (RFC 6901) refers to
+// in a JSON document, such as "/a/b~1c/0", where json[start:end] is the
+// complete value and 'info' is the info of the value, like Get.
+// The "~1" and "~0" escapes in the pointer are "/" and "~", and the keys in
+// the document are compared by their unescaped values, where the first of
+// duplicate keys is used. The empty pointer is the entire document.
+// It returns ErrInvalidPointer when the pointer is not valid, ErrNotFound
+// when the value is not in the document, such as for the "-" Array index, or
+// a *SyntaxError when the document is invalid before the value is found.
+func ResolvePointer[T Input](json T, ptr string) (start, end, info int,
+	err error,
+) {
+	if len(ptr) > 0 && ptr[0] != '/' {
+		return 0, 0, 0, ErrInvalidPointer
+	}
+	for i := 0; i < len(ptr); i++ {
+		if ptr[i] == '~' && (i+1 == len(ptr) ||
+			(ptr[i+1] != '0' && ptr[i+1] != '1')) {
+			return 0, 0, 0, ErrInvalidPointer
+		}
+	}
+	var path string
+	if len(ptr) > 0 {
+		path = ptr[1:]
+	}
+	start, end, info, ok := get(json, path, ptr == "", ptrkey)
+	if !ok {
+		_, err := syntaxErr(json, Limits{})
+		return 0, 0, 0, err
+	}
+	if info == 0 {
+		return 0, 0, 0, ErrNotFound
+	}
+	return start, end, info, nil
+}
+
+// ptrkey returns the first key of a pointer, without the leading '/' and
+// the '~' escapes, along with its Array index, and the rest of the pointer.
+// It returns false when the key is the last key.
+func ptrkey(ptr string) (key, rest string, index int, more bool) {
+	key = ptr
+	esc := false
+	for i := 0; i < len(ptr); i++ {
+		if ptr[i] == '~' {
+			esc = true
+		} else if ptr[i] == '/' {
+			key, rest, more = ptr[:i], ptr[i+1:], true
+			break
+		}
+	}
+	if !esc {
+		return key, rest, pathindex(key), more
+	}
+	buf := make([]byte, 0, len(key))
+	for i := 0; i < len(key); i++ {
+		if key[i] == '~' {
+			i++
+			if key[i] == '0' {
+				buf = append(buf, '~')
+			} else {
+				buf = append(buf, '/')
+			}
+		} else {
+			buf = append(buf, key[i])
+		}
+	}
+	return string(buf), rest, -1, more
+}
+
+// ParsePointers parses JSON like ParseErr, but the 'iter' function also
+// receives the JSON Pointer of each element, such that ResolvePointer with
+// the pointer returns the element's value, unless the value is in a member
+// with a duplicate key.
+// The pointer of a key is the pointer of its value. The Open and Close
+// elements have the pointer of their Object or Array, and the Comma, Colon
+// and Comment elements have the pointer of the Object or Array that they are
+// in.
+// The 'ptr' is only valid until 'iter' returns.
+func ParsePointers[T Input](json T, opts int,
+	iter func(start, end, info int, ptr []byte) int,
+) (int, error) {
+	var ptr, kbuf []byte
+	// The open containers, with the length of their pointer, and the index
+	// of the next element of an Array or -1 for an Object.
+	var stack []struct{ n, index int }
+	return ParseErr(json, opts, func(start, end, info int) int {
+		p := ptr
+		if len(stack) > 0 {
+			top := &stack[len(stack)-1]
+			switch {
+			case info&Close == Close:
+				ptr = ptr[:top.n]
+				p = ptr
+				stack = stack[:len(stack)-1]
+			case info&Key == Key:
+				ptr = append(ptr[:top.n], '/')
+				switch key := json[start:end]; {
+				case info&Ident == Ident:
+					ptr = appendptrkey(ptr, key)
+				case info&Escaped == Escaped:
+					kbuf, _ = AppendUnescaped(kbuf[:0], key)
+					ptr = appendptrkey(ptr, kbuf)
+				default:
+					ptr = appendptrkey(ptr, key[1:len(key)-1])
+				}
+				p = ptr
+			case info&Value == Value:
+				if top.index >= 0 {
+					ptr = strconv.AppendInt(append(ptr[:top.n], '/'),
+						int64(top.index), 10)
+					top.index++
+				}
+				p = ptr
+			default:
+				p = ptr[:top.n]
+			}
+		}
+		if info&Open == Open {
+			index := -1
+			if info&Array == Array {
+				index = 0
+			}
+			stack = append(stack, struct{ n, index int }{len(ptr), index})
+		}
+		return iter(start, end, info, p)
+	})
+}
+
+// appendptrkey appends a key to a pointer, with the '~' and '/' characters
+// escaped.
+func appendptrkey[T Input](dst []byte, key T) []byte {
+	for i := 0; i < len(key); i++ {
+		switch ch := key[i]; ch {
+		case '~':
+			dst = append(dst, '~', '0')
+		case '/':
+			dst = append(dst, '~', '1')
+		default:
+			dst = append(dst, ch)
+		}
+	}
+	return dst
+}
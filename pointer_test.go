@@ -0,0 +1,137 @@
+package pjson
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// rfc6901JSON is the example document from RFC 6901, with an escaped key.
+var rfc6901JSON = `{
+  "foo": ["bar", "baz"],
+  "": 0,
+  "a/b": 1,
+  "c%d": 2,
+  "e^f": 3,
+  "g|h": 4,
+  "i\\j": 5,
+  "k\"l": 6,
+  " ": 7,
+  "m~n": 8,
+  "o/p~": {"~1": [9]}
+}`
+
+func TestResolvePointer(t *testing.T) {
+	test := func(ptr, expect string, expectErr error) {
+		t.Helper()
+		start, end, info, err := ResolvePointer(rfc6901JSON, ptr)
+		if rfc6901JSON[start:end] != expect || err != expectErr ||
+			(err == nil) != (info != 0) {
+			t.Fatalf("%q: expected %s %v, got %s %v", ptr, expect, expectErr,
+				rfc6901JSON[start:end], err)
+		}
+	}
+	test("", rfc6901JSON, nil)
+	test("/foo", `["bar", "baz"]`, nil)
+	test("/foo/0", `"bar"`, nil)
+	test("/", `0`, nil)
+	test("/a~1b", `1`, nil)
+	test("/c%d", `2`, nil)
+	test("/e^f", `3`, nil)
+	test("/g|h", `4`, nil)
+	test(`/i\j`, `5`, nil)
+	test(`/k"l`, `6`, nil)
+	test("/ ", `7`, nil)
+	test("/m~0n", `8`, nil)
+	test("/o~1p~0/~01/0", `9`, nil)
+	test("/o~1p~0/~01", `[9]`, nil)
+	test("/foo/2", ``, ErrNotFound)
+	test("/foo/-", ``, ErrNotFound)
+	test("/foo/01", ``, ErrNotFound)
+	test("/foo/bar", ``, ErrNotFound)
+	test("/x", ``, ErrNotFound)
+	test("/a~1b/c", ``, ErrNotFound)
+	test("//", ``, ErrNotFound)
+	test("foo", ``, ErrInvalidPointer)
+	test("/~", ``, ErrInvalidPointer)
+	test("/~2", ``, ErrInvalidPointer)
+	test("/a~", ``, ErrInvalidPointer)
+
+	// The entire document has no Value bit, like Get.
+	if _, _, info, _ := ResolvePointer(rfc6901JSON, ""); info != Object {
+		t.Fatalf("expected %d, got %d", Object, info)
+	}
+	if _, _, info, _ := ResolvePointer(rfc6901JSON, "/foo"); info !=
+		Value|Array {
+		t.Fatalf("expected %d, got %d", Value|Array, info)
+	}
+
+	_, _, _, err := ResolvePointer([]byte(`{"a":[1,}`), "/b")
+	var serr *SyntaxError
+	if !errors.As(err, &serr) || serr.Offset != 8 {
+		t.Fatalf("expected a syntax error at 8, got %v", err)
+	}
+	start, end, _, err := ResolvePointer([]byte(`{"a":1,}`), "/a")
+	if start != 5 || end != 6 || err != nil {
+		t.Fatalf("expected 5 6, got %d %d %v", start, end, err)
+	}
+}
+
+func TestParsePointers(t *testing.T) {
+	json := `{"a":[1,{"b/c":true}],"~":{}}`
+	var out []string
+	n, err := ParsePointers(json, 0, func(start, end, info int,
+		ptr []byte) int {
+		out = append(out, fmt.Sprintf("%s=%s", json[start:end], ptr))
+		return 1
+	})
+	expect := `[{= "a"=/a := [=/a 1=/a/0 ,=/a {=/a/1 "b/c"=/a/1/b~1c ` +
+		`:=/a/1 true=/a/1/b~1c }=/a/1 ]=/a ,= "~"=/~0 := {=/~0 }=/~0 }=]`
+	if n != len(json) || err != nil || fmt.Sprint(out) != expect {
+		t.Fatalf("expected %s, got %v %d %v", expect, out, n, err)
+	}
+
+	// Every value resolves to itself with its pointer.
+	testRoundTrip := func(json []byte, opts int) {
+		t.Helper()
+		var opens []int
+		_, err := ParsePointers(json, opts, func(start, end, info int,
+			ptr []byte) int {
+			if info&Open == Open {
+				opens = append(opens, start)
+				return 1
+			}
+			if info&Close == Close {
+				start = opens[len(opens)-1]
+				opens = opens[:len(opens)-1]
+			} else if info&(Value|Start) == 0 {
+				return 1
+			}
+			s, e, _, err := ResolvePointer(json, string(ptr))
+			if s != start || e != end || err != nil {
+				t.Fatalf("%q: expected %d %d, got %d %d %v", ptr, start, end,
+					s, e, err)
+			}
+			return 1
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	testRoundTrip([]byte(rfc6901JSON), 0)
+	testRoundTrip([]byte(json1), 0)
+	testRoundTrip([]byte(`[[],[[{}]],""]`), 0)
+	json5 := []byte(`/* c */ {a: [1, 'x'], "b\u0000": 2,}`)
+	var ptrs []string
+	if _, err := ParsePointers(json5, AllowJSON5|ReportComments,
+		func(start, end, info int, ptr []byte) int {
+			ptrs = append(ptrs, string(ptr))
+			return 1
+		}); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprintf("%q", ptrs) != `["" "" "/a" "" "/a" "/a/0" "/a" "/a/1" `+
+		`"/a" "" "/b\x00" "" "/b\x00" "" ""]` {
+		t.Fatalf("got %q", ptrs)
+	}
+}
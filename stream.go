@@ -0,0 +1,968 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"errors"
+	"io"
+)
+
+// readSize is the initial size of the ParseReader buffer, and the minimum
+// room that is made in the buffer once it's full.
+const readSize = 64 * 1024
+
+// ParseReader parses a JSON document from an io.Reader.
+// This operation works like Parse, except that the document is read and
+// scanned in chunks and it's never required to be entirely in memory.
+// The 'start' and 'end' params of the 'iter' function are the absolute
+// offsets of the element in the document and the 'token' param is the
+// element data. The 'token' is only valid until 'iter' returns.
+// The return value has the same meaning as the value returned from Parse.
+// A non-nil error is only returned when the reader fails with an error
+// other than io.EOF.
+func ParseReader(r io.Reader, opts int,
+	iter func(start, end, info int, token []byte) int,
+) (int, error) {
+	var s stream[[]byte]
+	return readstream(&s, r, opts, iter)
+}
+
+// ParseReaderLimits parses a JSON document from an io.Reader like
+// ParseReader, but it fails with a *SyntaxError when the document is invalid
+// or exceeds a limit, like ParseLimits. The reader is not read past the
+// MaxBytes limit.
+// The returned value is the same as ParseLimits, and the error is either a
+// *SyntaxError or the error from the reader.
+func ParseReaderLimits(r io.Reader, opts int, limits Limits,
+	iter func(start, end, info int, token []byte) int,
+) (int, error) {
+	var s stream[[]byte]
+	s.limits = limits
+	n, err := readstream(&s, r, opts, iter)
+	if n < 0 {
+		n = -n
+	}
+	if err != nil {
+		return n, err
+	}
+	return n, s.err()
+}
+
+// readstream parses a document from a reader with the stream.
+func readstream(s *stream[[]byte], r io.Reader, opts int,
+	iter func(start, end, info int, token []byte) int,
+) (int, error) {
+	s.opts = opts
+	mem := make([]byte, readSize)
+	s.buf = mem[:0]
+	s.iter = s.wrap(iter)
+	var rerr error // read error, which is returned after the read bytes
+	for {
+		n, status := s.run()
+		if status != stMore {
+			return n, nil
+		}
+		if rerr != nil {
+			return n, rerr
+		}
+		s.advance()
+		if len(s.buf) == cap(s.buf) {
+			// The window is full, so move the pending bytes to the front
+			// of the buffer and make room for at least readSize bytes, or
+			// as many bytes as are pending, which ensures that very large
+			// tokens are not moved too often.
+			pending := len(s.buf)
+			need := pending + readSize
+			if pending > readSize {
+				need = pending * 2
+			}
+			if len(mem) < need {
+				mem = make([]byte, need)
+			}
+			copy(mem, s.buf)
+			s.buf = mem[:pending]
+		}
+		// Parse after every read, even a short one, such that the elements
+		// are not delayed by a reader that waits for more data.
+		p := s.buf[len(s.buf):cap(s.buf)]
+		if max := s.limits.MaxBytes; max > 0 &&
+			len(p) > max-s.base-len(s.buf) {
+			// Read at most one byte past the limit, which is enough to
+			// know that the document is too large.
+			p = p[:max-s.base-len(s.buf)+1]
+		}
+		n, err := r.Read(p)
+		s.buf = s.buf[:len(s.buf)+n]
+		s.truncate()
+		if err == io.EOF {
+			s.eof = true
+		} else if err != nil {
+			rerr = err
+		}
+	}
+}
+
+var (
+	// ErrIncomplete is returned by Parser.Close when the document ended
+	// before it was complete.
+	ErrIncomplete = errors.New("pjson: incomplete document")
+	// ErrInvalid is returned by the Parser when the document has invalid
+	// data.
+	ErrInvalid = errors.New("pjson: invalid document")
+)
+
+// Parser is a push parser that accepts a JSON document in chunks.
+// It keeps its position in the document between calls to Write, and it calls
+// the 'iter' function for every element that is complete, using the same
+// elements and return values as ParseReader.
+//
+//	p := NewParser(0, iter)
+//	for chunk := range chunks {
+//	    if _, err := p.Write(chunk); err != nil {
+//	        return err // errors.Is(err, ErrInvalid)
+//	    }
+//	}
+//	return p.Close() // errors.Is(err, ErrIncomplete)
+//
+// The errors returned by the Parser are of the type *SyntaxError.
+// Parser implements the io.WriteCloser interface.
+type Parser struct {
+	s       stream[[]byte]
+	mem     []byte // pending bytes that were retained from prior chunks
+	n       int    // result of the parsing, once finished
+	err     error  // error, once finished
+	stopped bool   // iter stopped the parsing, or the parsing is finished
+}
+
+// NewParser returns a new push parser. See Parse for details about the 'opts'
+// and 'iter' params, and ParseReader for the 'token' param of 'iter'.
+func NewParser(opts int,
+	iter func(start, end, info int, token []byte) int,
+) *Parser {
+	p := new(Parser)
+	p.s.opts = opts
+	p.s.iter = p.s.wrap(iter)
+	return p
+}
+
+// SetLimits sets the resource limits for the document, which must be done
+// before the first call to Write.
+func (p *Parser) SetLimits(limits Limits) {
+	p.s.limits = limits
+}
+
+// Write parses the next chunk of the document.
+// It returns an error when the document is known to be invalid, otherwise
+// it always consumes the entire chunk, even when the document cannot be
+// completed until more chunks are written.
+// Writing to a parser that is stopped or finished has no effect other than
+// returning the prior error, if any.
+func (p *Parser) Write(chunk []byte) (int, error) {
+	if p.stopped {
+		if p.err != nil {
+			return 0, p.err
+		}
+		return len(chunk), nil
+	}
+	s := &p.s
+	s.advance()
+	cstart := s.base + len(s.buf)
+	retained := len(s.buf) > 0
+	if !retained {
+		// Nothing is pending, so scan the chunk without copying it.
+		s.buf = chunk
+	} else {
+		s.buf = append(s.buf, chunk...)
+	}
+	s.truncate()
+	n, status := s.run()
+	switch status {
+	case stMore:
+		// Retain the pending bytes, which may belong to the caller's chunk.
+		// Bytes that are already retained are not copied again, such that
+		// a long token is not copied for every chunk.
+		s.advance()
+		if !retained {
+			p.mem = append(p.mem[:0], s.buf...)
+			s.buf = p.mem
+		}
+		return len(chunk), nil
+	case stError:
+		p.finish(n, s.err())
+		n = -n - cstart
+		if n < 0 {
+			n = 0
+		}
+		return n, p.err
+	}
+	p.finish(n, nil)
+	return len(chunk), nil
+}
+
+// Close tells the parser that the document is complete.
+// It returns an error when the document ended before it was complete, or
+// when the document is invalid.
+func (p *Parser) Close() error {
+	if !p.stopped {
+		p.s.eof = true
+		n, _ := p.s.run()
+		p.finish(n, p.s.err())
+	}
+	return p.err
+}
+
+func (p *Parser) finish(n int, err error) {
+	p.n = n
+	p.err = err
+	p.stopped = true
+	p.mem = nil
+	p.s.buf = nil
+	p.s.i = 0
+}
+
+// Offset returns the value that Parse would return for the document.
+// This is only meaningful after Close is called, or when Write returns an
+// error or the 'iter' function stops the parsing.
+func (p *Parser) Offset() int {
+	if p.stopped {
+		return p.n
+	}
+	return p.s.base + p.s.i
+}
+
+// stream states
+const (
+	sValue    = iota // expecting a value
+	sObjFirst        // expecting a key or '}'
+	sObjKey          // expecting a key
+	sColon           // expecting a ':'
+	sObjNext         // expecting a ',' or '}'
+	sArrFirst        // expecting a value or ']'
+	sArrNext         // expecting a ',' or ']'
+	sArrItem         // expecting a value, or ']' for JSON5
+	sEnd             // expecting the end of the document
+	sDone            // the document is complete
+)
+
+// stream status codes returned by step and run
+const (
+	stEvent = iota // an element was scanned
+	stMore         // more input is needed to continue
+	stDone         // the document is complete and valid
+	stError        // a syntax error was found at the current position
+	stStop         // the iter function stopped the parsing
+)
+
+// stream is a resumable parser that scans one element at a time from an
+// input window. The window may be refilled between steps, which allows for
+// parsing documents that arrive in chunks.
+// It follows the same grammar and reports the same elements and errors as
+// vdoc, but it keeps its position in the grammar on an explicit stack
+// rather than in the call stack.
+type stream[T Input] struct {
+	opts   int // parsing options
+	iter   func(start, end, info int) int
+	titer  func(tok Token) int
+	buf    T         // input window
+	base   int       // absolute offset of buf[0]
+	i      int       // current position in buf
+	eof    bool      // no more input will be appended to buf
+	state  int       // current grammar state
+	stack  []byte    // open containers, '{' or '['
+	mute   int       // depth of a container with muted children, or zero
+	limits Limits    // resource limits
+	kind   ErrorKind // kind of syntax error, on stError
+	errat  int       // offset of the syntax error from buf[i], on stError
+	line   int       // number of lines before buf[0]
+	lineAt int       // absolute offset of the line containing buf[0]
+
+	opens   []int // absolute offsets of the open containers
+	counts  []int // number of members of each open container
+	opened  int   // absolute offset of the Open element for the last Close
+	members int   // number of members for the last Close
+
+	// String or Number that continues past the window, on stMore
+	scanned int // number of bytes of the token that were already scanned
+	sinfo   int // info of the String that was already scanned
+
+	// Counters, for Limits
+	over  bool // the window was truncated at Limits.MaxBytes
+	ntoks int  // number of elements
+
+	// Object keys, for ReportDuplicates and RejectDuplicates
+	keys  []map[string]struct{} // keys of each open Object
+	nkeys int                   // number of open Objects with keys
+	kbuf  []byte                // unescaped key
+}
+
+// parse parses an entire document with the stream.
+func (s *stream[T]) parse(json T, opts int) int {
+	s.opts = opts
+	s.buf = json
+	s.eof = true
+	s.truncate()
+	n, _ := s.run()
+	return n
+}
+
+// wrap returns an iter function that calls an iter function which has the
+// token param.
+func (s *stream[T]) wrap(iter func(start, end, info int, token T) int,
+) func(start, end, info int) int {
+	if iter == nil {
+		return nil
+	}
+	return func(start, end, info int) int {
+		return iter(start, end, info, s.buf[start-s.base:end-s.base])
+	}
+}
+
+// truncate truncates the window at the MaxBytes limit.
+func (s *stream[T]) truncate() {
+	if max := s.limits.MaxBytes; max > 0 && s.base+len(s.buf) > max {
+		s.buf = s.buf[:max-s.base]
+		s.eof = false
+		s.over = true
+	}
+}
+
+// advance discards the window data before the current position.
+func (s *stream[T]) advance() {
+	if n, j := newlines(s.buf[:s.i]); n > 0 {
+		s.line += n
+		s.lineAt = s.base + j + 1
+	}
+	s.base += s.i
+	s.buf = s.buf[s.i:]
+	s.i = 0
+}
+
+// err returns the syntax error, on stError.
+func (s *stream[T]) err() error {
+	if s.kind == 0 {
+		return nil
+	}
+	return newSyntaxError(s.kind, s.buf, s.i+s.errat, s.base, s.line,
+		s.lineAt)
+}
+
+// fail sets the syntax error at buf[i].
+func (s *stream[T]) fail(i int, kind ErrorKind) (start, end, info, status int) {
+	if i >= len(s.buf) && kind != UnterminatedString &&
+		kind != UnterminatedComment {
+		kind = UnexpectedEOF
+	}
+	s.i = i
+	s.kind = kind
+	return 0, 0, 0, stError
+}
+
+// strfail sets the syntax error at buf[i] that was found by vstring.
+func (s *stream[T]) strfail(i int) (start, end, info, status int) {
+	if i >= len(s.buf) {
+		return s.fail(i, UnterminatedString)
+	}
+	if s.buf[i] < ' ' {
+		return s.fail(i, UnexpectedChar)
+	}
+	if s.buf[i] >= 0x80 {
+		return s.fail(i, InvalidUTF8)
+	}
+	return s.fail(i, BadEscape)
+}
+
+// surrogatefail sets the syntax error at the first lone surrogate escape in
+// the String at buf[start:end].
+func (s *stream[T]) surrogatefail(start, end int) (int, int, int, int) {
+	if i := lonesurrogate(s.buf, start, end); i >= 0 {
+		return s.fail(i, BadSurrogate)
+	}
+	return s.fail(end, BadSurrogate)
+}
+
+// lonesurrogate returns the position of the first lone surrogate escape in
+// the String at json[start:end], or -1 if there is none.
+func lonesurrogate[T Input](json T, start, end int) int {
+	json = json[:end]
+	for i := start + 1; i < end; i++ {
+		if json[i] != '\\' {
+			continue
+		}
+		i++
+		if json[i] == 'u' {
+			var paired bool
+			if i, paired = vsurrogate(json, i+4); !paired {
+				return i - 5
+			}
+		}
+	}
+	return -1
+}
+
+// more returns true if a token that was scanned up to position 'end' may
+// continue past the current window.
+func (s *stream[T]) more(end int) bool {
+	return end >= len(s.buf) && !s.eof
+}
+
+// next updates the state for the element following a value.
+func (s *stream[T]) next() {
+	if len(s.stack) == 0 {
+		s.state = sEnd
+	} else if s.stack[len(s.stack)-1] == '{' {
+		s.state = sObjNext
+	} else {
+		s.state = sArrNext
+	}
+}
+
+// ws skips the whitespace starting at buf[i]. It returns false if more input
+// is needed to know where the whitespace ends.
+func (s *stream[T]) ws(i int) (int, bool) {
+	if i < len(s.buf) && s.buf[i] > ' ' && s.buf[i] < 0x80 {
+		// not whitespace, which is the common case
+		return i, true
+	}
+	return s.wsslow(i)
+}
+
+func (s *stream[T]) wsslow(i int) (int, bool) {
+	json := s.buf
+	for ; i < len(json); i++ {
+		if isws(json[i]) {
+			continue
+		}
+		if s.opts&AllowJSON5 == 0 {
+			break
+		}
+		n := ws5(json, i)
+		if n == 0 {
+			break
+		}
+		if n == -1 {
+			if s.eof {
+				break
+			}
+			return i, false
+		}
+		i += n - 1
+	}
+	return i, true
+}
+
+// step scans the next element in the window. On stEvent the element is
+// buf[start:end]. On stMore the window must be refilled with the bytes
+// starting at buf[s.i]. On stError the error is at buf[s.i].
+func (s *stream[T]) step() (start, end, info, status int) {
+	json := s.buf
+again:
+	i, ok := s.ws(s.i)
+	s.i = i
+	if !ok {
+		return 0, 0, 0, stMore
+	}
+	if i == len(json) {
+		if !s.eof {
+			return 0, 0, 0, stMore
+		}
+		if s.state == sEnd {
+			s.state = sDone
+			return 0, 0, 0, stDone
+		}
+		return s.fail(i, UnexpectedEOF)
+	}
+	if json[i] == '/' &&
+		s.opts&(AllowJSON5|AllowComments|ReportComments) != 0 {
+		end, ok := vcomment(json, i+1)
+		if s.more(end) {
+			return 0, 0, 0, stMore
+		}
+		if !ok {
+			if end == len(json) && i+1 < len(json) && json[i+1] == '*' {
+				return s.fail(end, UnterminatedComment)
+			}
+			return s.fail(end, UnexpectedChar)
+		}
+		s.i = end
+		if s.opts&ReportComments != 0 {
+			return i, end, Comment, stEvent
+		}
+		goto again
+	}
+	json5 := s.opts&AllowJSON5 != 0
+	switch s.state {
+	case sObjFirst, sObjKey:
+		if json[i] == '}' && (s.state == sObjFirst || json5) {
+			return s.close(Object)
+		}
+		return s.key()
+	case sColon:
+		if json[i] != ':' {
+			return s.fail(i, UnexpectedChar)
+		}
+		s.i = i + 1
+		s.state = sValue
+		return i, i + 1, Colon, stEvent
+	case sObjNext, sArrNext:
+		if json[i] == ',' {
+			s.i = i + 1
+			if s.state == sObjNext {
+				s.state = sObjKey
+			} else {
+				s.state = sArrItem
+			}
+			return i, i + 1, Comma, stEvent
+		}
+		if s.state == sObjNext && json[i] == '}' {
+			return s.close(Object)
+		}
+		if s.state == sArrNext && json[i] == ']' {
+			return s.close(Array)
+		}
+		return s.fail(i, UnexpectedChar)
+	case sArrFirst, sArrItem:
+		if json[i] == ']' && (s.state == sArrFirst || json5) {
+			return s.close(Array)
+		}
+	case sEnd, sDone:
+		return s.fail(i, TrailingData)
+	}
+	return s.value()
+}
+
+// key scans the Object key at the current position.
+func (s *stream[T]) key() (start, end, info, status int) {
+	json := s.buf
+	i := s.i
+	switch {
+	case json[i] == '"', json[i] == '\'' && s.opts&AllowJSON5 != 0:
+		return s.str(Key | String)
+	case isidentstart(json[i]) && s.opts&AllowJSON5 != 0:
+		end, info, ok := videntifier(json, i, s.opts&ValidateUTF8 != 0)
+		if max := s.limits.MaxStringLen; max > 0 && end-i > max {
+			return s.fail(i, StringTooLong)
+		}
+		if s.more(end) {
+			return 0, 0, 0, stMore
+		}
+		if !ok {
+			return s.fail(end, InvalidUTF8)
+		}
+		return s.endkey(i, end, info|Key|Ident)
+	}
+	return s.fail(i, UnexpectedChar)
+}
+
+// endkey completes the Object key at buf[start:end].
+func (s *stream[T]) endkey(start, end, info int) (int, int, int, int) {
+	if s.opts&(ReportDuplicates|RejectDuplicates) != 0 && s.dupkey(start, end,
+		info) {
+		if s.opts&RejectDuplicates != 0 {
+			return s.fail(start, DuplicateKey)
+		}
+		info |= Duplicate
+	}
+	s.i = end
+	s.state = sColon
+	return start, end, info, stEvent
+}
+
+// pushkeys starts tracking the keys of a new Object.
+func (s *stream[T]) pushkeys() {
+	if s.nkeys == len(s.keys) {
+		s.keys = append(s.keys, make(map[string]struct{}))
+	} else {
+		for key := range s.keys[s.nkeys] {
+			delete(s.keys[s.nkeys], key)
+		}
+	}
+	s.nkeys++
+}
+
+// dupkey returns true if the Object key at buf[start:end] is already in the
+// current Object, otherwise the key is added to the Object.
+// Keys are compared by their unescaped values, and Strings with lone
+// surrogates are compared as is, because they are not unescaped exactly.
+func (s *stream[T]) dupkey(start, end, info int) bool {
+	key := s.buf[start:end]
+	keys := s.keys[s.nkeys-1]
+	if info&Ident == 0 {
+		if info&Escaped != 0 && info&LoneSurrogate == 0 {
+			s.kbuf, _ = AppendUnescaped(s.kbuf[:0], key)
+			return addkey(keys, s.kbuf)
+		}
+		key = key[1 : len(key)-1]
+	}
+	return addkey(keys, key)
+}
+
+// addkey returns true if the key is already in keys, otherwise the key is
+// added to keys.
+func addkey[T Input](keys map[string]struct{}, key T) bool {
+	if _, ok := keys[string(key)]; ok {
+		return true
+	}
+	keys[string(key)] = struct{}{}
+	return false
+}
+
+// vstr scans the String that starts at buf[start], from buf[i].
+func (s *stream[T]) vstr(start, i int) (end, info int, ok bool) {
+	json := s.buf
+	utf8 := s.opts&ValidateUTF8 != 0
+	if s.opts&AllowJSON5 != 0 {
+		end, info, ok = vstring5(json, i, json[start], utf8)
+		if json[start] == '\'' {
+			info |= SingleQuote
+		}
+	} else if utf8 {
+		end, info, ok, _ = vstring(json, i, &strtoks8)
+	} else {
+		end, info, ok, _ = vstring(json, i, &strtoks)
+	}
+	return end, info, ok
+}
+
+// str scans the String at the current position, which is a key or a value
+// depending on 'dinfo'.
+func (s *stream[T]) str(dinfo int) (start, end, info, status int) {
+	json := s.buf
+	i := s.i
+	end, info, ok := s.vstr(i, i+1+s.scanned)
+	info |= s.sinfo
+	max := s.limits.MaxStringLen
+	if !ok {
+		if end >= len(json) && max > 0 && end-i-1 > max {
+			// the String is already too long
+			s.scanned, s.sinfo = 0, 0
+			return s.fail(i, StringTooLong)
+		}
+		if s.more(end) {
+			// Resume the scan before the escape or UTF-8 sequence that may
+			// be incomplete at the end of the window, but not after a '\'.
+			j := end - 6
+			for j > i+1 && json[j]&0xC0 == 0x80 {
+				j--
+			}
+			if j > i+1 && json[j] == '\n' && json[j-1] == '\r' {
+				// JSON5 line continuation
+				j--
+			}
+			if j > i+1 && json[j-1] == '\\' {
+				n := 1
+				for json[j-1-n] == '\\' {
+					n++
+				}
+				if n%2 == 1 {
+					j--
+				}
+			}
+			if j > i+1+s.scanned {
+				s.scanned = j - i - 1
+			}
+			// The info of the bytes after the resume position is found
+			// again, which is the same, except for LoneSurrogate.
+			s.sinfo = info
+			return 0, 0, 0, stMore
+		}
+	}
+	if s.scanned > 0 || s.sinfo != 0 {
+		// A surrogate pair that was split by the end of the window, or by
+		// the resume position, is a lone surrogate in one of the parts.
+		if info&LoneSurrogate != 0 && ok && lonesurrogate(json, i, end) < 0 {
+			info &^= LoneSurrogate
+		}
+		s.scanned, s.sinfo = 0, 0
+	}
+	if !ok {
+		return s.strfail(end)
+	}
+	if max > 0 && end-i-2 > max {
+		return s.fail(i, StringTooLong)
+	}
+	if info&LoneSurrogate != 0 && s.opts&RejectSurrogates != 0 {
+		return s.surrogatefail(i, end)
+	}
+	if dinfo&Key == Key {
+		return s.endkey(i, end, info|dinfo)
+	}
+	return s.scalar(end, info|dinfo)
+}
+
+// value scans the value at the current position.
+func (s *stream[T]) value() (start, end, info, status int) {
+	json := s.buf
+	i := s.i
+	dinfo := Value
+	if len(s.stack) == 0 {
+		dinfo = Start
+	}
+	var ok bool
+	var lit string
+	switch json[i] {
+	case '{', '[':
+		if max := s.limits.maxDepth(); max >= 0 && len(s.stack) >= max {
+			return s.fail(i, DepthExceeded)
+		}
+		s.stack = append(s.stack, json[i])
+		s.opens = append(s.opens, s.base+i)
+		s.counts = append(s.counts, 0)
+		s.i = i + 1
+		if json[i] == '{' {
+			if s.opts&(ReportDuplicates|RejectDuplicates) != 0 {
+				s.pushkeys()
+			}
+			s.state = sObjFirst
+			return i, i + 1, Object | Open | dinfo, stEvent
+		}
+		s.state = sArrFirst
+		return i, i + 1, Array | Open | dinfo, stEvent
+	case '"':
+		return s.str(dinfo | String)
+	case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+		return s.number(dinfo)
+	case 't':
+		lit = "true"
+		if s.partial(lit) {
+			return 0, 0, 0, stMore
+		}
+		end, ok, _ = vtrue(json, i+1)
+		info = True
+	case 'f':
+		lit = "false"
+		if s.partial(lit) {
+			return 0, 0, 0, stMore
+		}
+		end, ok, _ = vfalse(json, i+1)
+		info = False
+	case 'n':
+		lit = "null"
+		if s.partial(lit) {
+			return 0, 0, 0, stMore
+		}
+		end, ok, _ = vnull(json, i+1)
+		info = Null
+	default:
+		if s.opts&AllowJSON5 != 0 {
+			switch json[i] {
+			case '\'':
+				return s.str(dinfo | String)
+			case '+', '.', 'I', 'N':
+				return s.number(dinfo)
+			}
+		}
+		return s.fail(i, UnexpectedChar)
+	}
+	if !ok {
+		// The error is at the first byte that does not match the literal,
+		// but the position is after the first byte, like Parse.
+		end = litfail(json, i, lit)
+		s.fail(end, UnexpectedChar)
+		s.i, s.errat = i+1, end-i-1
+		return 0, 0, 0, stError
+	}
+	return s.scalar(end, info|dinfo)
+}
+
+// number scans the Number at the current position.
+func (s *stream[T]) number(dinfo int) (start, end, info, status int) {
+	if s.scanned > 0 {
+		// Skip the digits that follow the part of the Number that was
+		// scanned before, and scan the entire Number again after them.
+		end = s.i + s.scanned
+		for end < len(s.buf) && isnum(s.buf[end]) {
+			end++
+		}
+		if max := s.limits.MaxNumberLen; max > 0 && end-s.i > max {
+			s.scanned = 0
+			return s.fail(s.i, NumberTooLong)
+		}
+		if s.more(end) {
+			s.scanned = end - s.i
+			return 0, 0, 0, stMore
+		}
+		s.scanned = 0
+	}
+	var ok bool
+	if s.opts&AllowJSON5 != 0 {
+		end, info, ok = vnumber5(s.buf, s.i)
+	} else {
+		end, info, ok, _ = vnumber(s.buf, s.i+1)
+	}
+	if max := s.limits.MaxNumberLen; max > 0 && end-s.i > max {
+		return s.fail(s.i, NumberTooLong)
+	}
+	if s.more(end) {
+		// More digits can follow, unless the Number is a leading zero.
+		j := end
+		for j > s.i && isnum(s.buf[j-1]) {
+			j--
+		}
+		if j < end && (s.buf[j] != '0' || end-j > 1 ||
+			(j > s.i && s.buf[j-1] != '-' && s.buf[j-1] != '+')) {
+			s.scanned = end - s.i
+		}
+		return 0, 0, 0, stMore
+	}
+	if !ok {
+		return s.fail(end, BadNumber)
+	}
+	return s.scalar(end, info|Number|dinfo)
+}
+
+// scalar completes the scalar value from the current position to buf[end].
+func (s *stream[T]) scalar(end, info int) (int, int, int, int) {
+	start := s.i
+	s.i = end
+	if info&Start == Start {
+		info |= End
+	}
+	s.next()
+	return start, end, info, stEvent
+}
+
+// partial returns true if the window ends with an incomplete prefix of the
+// literal at the current position.
+func (s *stream[T]) partial(lit string) bool {
+	rest := s.buf[s.i:]
+	if len(rest) >= len(lit) || s.eof {
+		return false
+	}
+	return string(rest) == lit[:len(rest)]
+}
+
+// close scans the close character at the current position.
+func (s *stream[T]) close(kind int) (start, end, info, status int) {
+	i := s.i
+	s.stack = s.stack[:len(s.stack)-1]
+	s.opened = s.opens[len(s.opens)-1]
+	s.opens = s.opens[:len(s.opens)-1]
+	s.members = s.counts[len(s.counts)-1]
+	s.counts = s.counts[:len(s.counts)-1]
+	if kind == Object && s.opts&(ReportDuplicates|RejectDuplicates) != 0 {
+		s.nkeys--
+	}
+	s.i = i + 1
+	info = kind | Close | Value
+	if len(s.stack) == 0 {
+		info = kind | Close | End
+	}
+	s.next()
+	return i, i + 1, info, stEvent
+}
+
+// count counts an element, for the MaxTokens and MaxMembers limits, and for
+// the Index and Count of the Token. It returns the kind of error when a limit
+// is exceeded.
+func (s *stream[T]) count(info int) ErrorKind {
+	s.ntoks++
+	if max := s.limits.MaxTokens; max > 0 && s.ntoks > max {
+		return TooManyTokens
+	}
+	if info&(Key|Value) != 0 && info&Close == 0 {
+		// Keys are Object members and Values in Arrays are elements.
+		top := len(s.stack) - 1
+		if info&Open == Open {
+			top--
+		}
+		if info&Key == Key || s.stack[top] == '[' {
+			s.counts[top]++
+			if max := s.limits.MaxMembers; max > 0 && s.counts[top] > max {
+				return TooManyMembers
+			}
+		}
+	}
+	return 0
+}
+
+// run scans elements and calls iter for each, until the window is exhausted,
+// the document is complete, a syntax error is found, or iter stops. The
+// returned value is the same value that Parse would return, with the status
+// stMore meaning that the window needs to be refilled.
+func (s *stream[T]) run() (int, int) {
+	for {
+		start, end, info, status := s.event()
+		switch status {
+		case stMore:
+			return s.base + s.i, stMore
+		case stDone:
+			return s.base + len(s.buf), stDone
+		case stError:
+			return -(s.base + s.i), stError
+		}
+		if s.iter == nil && s.titer == nil {
+			continue
+		}
+		if s.mute != 0 {
+			if len(s.stack) >= s.mute {
+				continue
+			}
+			s.mute = 0
+		}
+		var r int
+		if s.titer != nil {
+			r = s.titer(s.token(start, end, info))
+		} else {
+			r = s.iter(s.base+start, s.base+end, info)
+		}
+		if r == 0 {
+			// Stopping on an open or comma element reports the start
+			// position, like Parse.
+			if info&(Open|Comma) != 0 {
+				return s.base + start, stStop
+			}
+			return s.base + end, stStop
+		}
+		if r == -1 && info&Open == Open {
+			s.mute = len(s.stack)
+		}
+	}
+}
+
+// event scans the next element like step, and it also checks the limits that
+// apply to the elements.
+func (s *stream[T]) event() (start, end, info, status int) {
+	start, end, info, status = s.step()
+	switch status {
+	case stMore:
+		if s.over {
+			s.i = len(s.buf)
+			s.kind = DocumentTooLarge
+			return 0, 0, 0, stError
+		}
+	case stEvent:
+		if kind := s.count(info); kind != 0 {
+			s.i = start
+			s.kind = kind
+			return 0, 0, 0, stError
+		}
+	}
+	return start, end, info, status
+}
+
+// token returns the Token for the element at buf[start:end].
+func (s *stream[T]) token(start, end, info int) Token {
+	depth := len(s.stack)
+	if info&Open == Open {
+		depth--
+	}
+	tok := Token{
+		Start: s.base + start,
+		End:   s.base + end,
+		Info:  info,
+		Depth: depth,
+	}
+	if info&Open == Open {
+		tok.OpenStart = tok.Start
+	} else if info&Close == Close {
+		tok.OpenStart = s.opened
+		tok.Count = s.members
+	}
+	tok.Index = -1
+	if parent := depth - 1; info&Value == Value && s.stack[parent] == '[' {
+		tok.Index = s.counts[parent] - 1
+	}
+	return tok
+}
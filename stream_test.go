@@ -0,0 +1,430 @@
+package pjson
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+var streamDocs = []string{
+	``, ` `, ` a`, `false`, ` false `, `fals`, `tru`, `nul`, `fals0`,
+	`-`, `-1`, `-1.`, `-1.0e`, `-1.0e+12`, `00`, `123 `, `123 4`,
+	`""`, `"`, `"\n"`, `"\"`, `"a\\b\\ﾪa"`, `"a\\b\\\uFFAZa"`, `"\u00`,
+	`"hello` + string(byte(0)) + `world"`, `[`, `[1`, `[1,`, `[1,]`, `[]`,
+	`{`, `{}`, `{"a"}`, `{"a":}`, `{"a":1,}`, `{"a":1 "b":2}`, `{123:123}`,
+	` { "hello" : [ 1, 2, 3 ], "jello" : [ 4, 5, 6 ] } `,
+	`[[[[{"a":[{"b":null}]}]]]] x`, ` {"hel\y" : 1}`,
+	json1, json2,
+}
+
+type streamEvent struct {
+	start, end, info int
+	token            string
+}
+
+func collectParse(json []byte, ret func(info int) int) ([]streamEvent, int) {
+	var evs []streamEvent
+	n := Parse(json, 0, func(start, end, info int) int {
+		evs = append(evs, streamEvent{start, end, info,
+			string(json[start:end])})
+		return ret(info)
+	})
+	return evs, n
+}
+
+func collectReader(r io.Reader, ret func(info int) int,
+) ([]streamEvent, int) {
+	var evs []streamEvent
+	n, err := ParseReader(r, 0, func(start, end, info int, token []byte) int {
+		evs = append(evs, streamEvent{start, end, info, string(token)})
+		return ret(info)
+	})
+	if err != nil {
+		panic(err)
+	}
+	return evs, n
+}
+
+func compareEvents(t *testing.T, json []byte, r io.Reader,
+	ret func(info int) int,
+) {
+	t.Helper()
+	evs1, n1 := collectParse(json, ret)
+	evs2, n2 := collectReader(r, ret)
+	if n1 != n2 {
+		t.Fatalf("%q: expected %d, got %d", json, n1, n2)
+	}
+	if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+		t.Fatalf("%q: expected %v, got %v", json, evs1, evs2)
+	}
+}
+
+func TestParseReader(t *testing.T) {
+	rets := []func(info int) int{
+		func(info int) int { return 1 },
+		func(info int) int { return -1 },
+		func(info int) int {
+			if info&Comma == Comma {
+				return 0
+			}
+			return 1
+		},
+		func(info int) int {
+			if info&(Array|Open) == Array|Open {
+				return -1
+			}
+			if info&Number == Number {
+				return 0
+			}
+			return 1
+		},
+	}
+	for _, doc := range streamDocs {
+		json := []byte(doc)
+		for _, ret := range rets {
+			compareEvents(t, json, bytes.NewReader(json), ret)
+			compareEvents(t, json,
+				iotest.OneByteReader(bytes.NewReader(json)), ret)
+			compareEvents(t, json,
+				iotest.DataErrReader(bytes.NewReader(json)), ret)
+		}
+	}
+}
+
+func TestParseReaderFiles(t *testing.T) {
+	fis, err := ioutil.ReadDir("testfiles")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range fis {
+		json, err := ioutil.ReadFile(filepath.Join("testfiles", fi.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		ret := func(info int) int { return 1 }
+		compareEvents(t, json, bytes.NewReader(json), ret)
+		compareEvents(t, json, iotest.HalfReader(bytes.NewReader(json)), ret)
+	}
+}
+
+func TestParseReaderLongTokens(t *testing.T) {
+	long := strings.Repeat("a", readSize*3+7)
+	digits := strings.Repeat("1", readSize*2+3)
+	json := []byte(`["` + long + `",` + digits + `.` + digits + `,"` +
+		long + `ሴ"]`)
+	compareEvents(t, json, iotest.HalfReader(bytes.NewReader(json)),
+		func(info int) int { return 1 })
+	// The long tokens are not scanned again from their start after every
+	// short read.
+	compareEvents(t, json, iotest.OneByteReader(bytes.NewReader(json)),
+		func(info int) int { return 1 })
+}
+
+// openReader returns its data and then fails the test, like a connection
+// that is held open after the document.
+type openReader struct {
+	t    *testing.T
+	data []byte
+}
+
+func (r *openReader) Read(p []byte) (int, error) {
+	if len(r.data) == 0 {
+		r.t.Fatal("read past the document")
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+func TestParseReaderOpen(t *testing.T) {
+	// The elements are parsed as soon as they are read.
+	json := []byte(`{"a":"hello","b":[1,2,3]}`)
+	var evs []streamEvent
+	n, err := ParseReader(iotest.OneByteReader(&openReader{t, json}), 0,
+		func(start, end, info int, token []byte) int {
+			evs = append(evs, streamEvent{start, end, info, string(token)})
+			if info&End == End {
+				return 0
+			}
+			return 1
+		})
+	evs1, _ := collectParse(json, func(info int) int { return 1 })
+	if n != len(json) || err != nil || fmt.Sprint(evs) != fmt.Sprint(evs1) {
+		t.Fatalf("expected %v, got %v %d %v", evs1, evs, n, err)
+	}
+}
+
+func TestParseReaderError(t *testing.T) {
+	r := iotest.TimeoutReader(strings.NewReader(`[1,2`))
+	n, err := ParseReader(r, 0, nil)
+	if err != iotest.ErrTimeout || n != 3 {
+		t.Fatalf("expected %v at 3, got %v at %d", iotest.ErrTimeout, err, n)
+	}
+}
+
+func collectParser(json []byte, size int, ret func(info int) int,
+) ([]streamEvent, int, error) {
+	var evs []streamEvent
+	p := NewParser(0, func(start, end, info int, token []byte) int {
+		evs = append(evs, streamEvent{start, end, info, string(token)})
+		return ret(info)
+	})
+	for i := 0; i < len(json); i += size {
+		chunk := json[i:]
+		if len(chunk) > size {
+			chunk = chunk[:size]
+		}
+		// Use a copy of the chunk that is overwritten after it's written.
+		chunk = append([]byte(nil), chunk...)
+		n, err := p.Write(chunk)
+		for j := range chunk {
+			chunk[j] = 'x'
+		}
+		if err != nil {
+			if n < 0 || n > len(chunk) {
+				panic("bad write count")
+			}
+			return evs, p.Offset(), err
+		}
+	}
+	err := p.Close()
+	return evs, p.Offset(), err
+}
+
+func TestParser(t *testing.T) {
+	for _, doc := range streamDocs {
+		json := []byte(doc)
+		for _, size := range []int{1, 2, 3, 7, 64, len(json) + 1} {
+			for _, ret := range []func(info int) int{
+				func(info int) int { return 1 },
+				func(info int) int { return -1 },
+			} {
+				evs1, n1 := collectParse(json, ret)
+				evs2, n2, err := collectParser(json, size, ret)
+				if n1 != n2 {
+					t.Fatalf("%q: expected %d, got %d", json, n1, n2)
+				}
+				if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+					t.Fatalf("%q: expected %v, got %v", json, evs1, evs2)
+				}
+				if (n1 > 0) != (err == nil) {
+					t.Fatalf("%q: unexpected error %v", json, err)
+				}
+			}
+		}
+	}
+}
+
+func TestParserSplitTokens(t *testing.T) {
+	// Strings and Numbers that continue past a chunk are resumed where the
+	// scanning stopped, which must find the same elements and errors.
+	str := strings.Repeat(`ab\\\"\u00e9\uD83D\uDE00é😀\/`, 20)
+	num := strings.Repeat("1234567890", 20)
+	docs := []string{
+		`["` + str + `","` + str + `\x"]`,
+		`["` + str + "\xff" + `"]`, `["` + str + "\xc3\x28" + `"]`,
+		`["` + str + `\u00ez"]`, `["` + str + `\uD83D\uDEz0"]`,
+		`["` + str + "\x01" + `"]`,
+		`["` + str + `\uD83D` + str + `"]`,
+		`["` + str + `\uD83D\u00e9"]`,
+		`{"` + str + `":"` + str + `"}`,
+		`[` + num + `.` + num + `e+` + num + `,-` + num + `]`,
+		`[0,-0,0.0,-0e0,10,100]`, `[` + num + `x]`, `[00]`, `[-01]`,
+		`[1.5e` + num + `.]`, `[` + num + `]`,
+	}
+	docs5 := []string{
+		`['` + str + `\'\\\` + "\r\n" + `\x41\0` + str + `']`,
+		`['` + str + `\01']`, `[0x` + num + `ABcdef,+` + num + `.5e3]`,
+		`[+0,-00,.` + num + `]`,
+	}
+	for _, opts := range []int{0, ValidateUTF8, ValidateUTF8 |
+		RejectSurrogates, AllowJSON5, AllowJSON5 | ValidateUTF8} {
+		all := docs
+		if opts&AllowJSON5 != 0 {
+			all = append(all[:len(all):len(all)], docs5...)
+		}
+		for _, doc := range all {
+			json := []byte(doc)
+			var evs1 []streamEvent
+			n1, err1 := ParseErr(json, opts, func(start, end, info int) int {
+				evs1 = append(evs1, streamEvent{start, end, info,
+					string(json[start:end])})
+				return 1
+			})
+			for size := 1; size <= 13; size++ {
+				var evs2 []streamEvent
+				p := NewParser(opts, func(start, end, info int,
+					token []byte) int {
+					evs2 = append(evs2, streamEvent{start, end, info,
+						string(token)})
+					return 1
+				})
+				var err2 error
+				for i := 0; i < len(json) && err2 == nil; i += size {
+					chunk := json[i:]
+					if len(chunk) > size {
+						chunk = chunk[:size]
+					}
+					_, err2 = p.Write(chunk)
+				}
+				if err2 == nil {
+					err2 = p.Close()
+				}
+				if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+					t.Fatalf("%q %d: expected %v, got %v", json, size, evs1,
+						evs2)
+				}
+				if fmt.Sprint(err1) != fmt.Sprint(err2) ||
+					(err1 == nil) != (p.Offset() == n1) {
+					t.Fatalf("%q %d: expected %v at %d, got %v at %d", json,
+						size, err1, n1, err2, p.Offset())
+				}
+			}
+		}
+	}
+}
+
+func TestParserIncomplete(t *testing.T) {
+	for _, doc := range []string{``, ` `, `[`, `[1`, `[1,`, `{"a`, `{"a"`,
+		`{"a":`, `{"a":1`, `"\u00`, `-`, `1.`, `1e+`, `t`, `tr`, `fals`,
+		`[[[[`, `{"a":[1,{"b":null}`,
+	} {
+		p := NewParser(0, nil)
+		for i := 0; i < len(doc); i++ {
+			if _, err := p.Write([]byte(doc[i : i+1])); err != nil {
+				t.Fatalf("%q: unexpected error %v", doc, err)
+			}
+		}
+		if err := p.Close(); !errors.Is(err, ErrIncomplete) {
+			t.Fatalf("%q: expected %v, got %v", doc, ErrIncomplete, err)
+		}
+	}
+	for _, doc := range []string{`x`, `[1,]`, `[1 2`, `tx`, `{"a":1}x`,
+		`"a` + string(byte(1)), `{1`, `--`, `1.e`, `01`,
+		`"aaaaaaaa\u12z`, `"aaaaaaaa\uD83D\uDz`, `"aaaaaaaa\\\x`,
+	} {
+		p := NewParser(0, nil)
+		var err error
+		for i := 0; i < len(doc) && err == nil; i++ {
+			_, err = p.Write([]byte(doc[i : i+1]))
+		}
+		if !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%q: expected %v, got %v", doc, ErrInvalid, err)
+		}
+		if err := p.Close(); !errors.Is(err, ErrInvalid) {
+			t.Fatalf("%q: expected %v, got %v", doc, ErrInvalid, err)
+		}
+	}
+	p := NewParser(0, nil)
+	p.Write([]byte(`{"a":[1,2,3]} `))
+	p.Write([]byte("\n"))
+	if err := p.Close(); err != nil || p.Offset() != 15 {
+		t.Fatalf("expected nil at 15, got %v at %d", err, p.Offset())
+	}
+}
+
+func TestComments(t *testing.T) {
+	json := []byte("// settings\n{/**/\"a\": /* one */ 1, // end\r\n" +
+		"\"b\"/*\n*/:[2 /* two */]}// done")
+	var out []string
+	n := Parse(json, ReportComments, func(start, end, info int) int {
+		if info&Comment == Comment {
+			out = append(out, string(json[start:end]))
+		}
+		return 1
+	})
+	if n != len(json) {
+		t.Fatalf("expected %d, got %d", len(json), n)
+	}
+	expect := []string{"// settings", "/**/", "/* one */", "// end",
+		"/*\n*/", "/* two */", "// done"}
+	if fmt.Sprint(out) != fmt.Sprint(expect) {
+		t.Fatalf("expected %q, got %q", expect, out)
+	}
+
+	// Comments are skipped with AllowComments and AllowJSON5.
+	for _, opts := range []int{AllowComments, AllowJSON5} {
+		var out []byte
+		n := Parse(json, opts, func(start, end, info int) int {
+			out = append(out, json[start:end]...)
+			return 1
+		})
+		if n != len(json) || string(out) != `{"a":1,"b":[2]}` {
+			t.Fatalf("expected %d, got %d %q", len(json), n, out)
+		}
+	}
+	if Parse(json, 0, nil) > 0 {
+		t.Fatal("expected invalid strict json")
+	}
+
+	// Comments within skipped children are not reported.
+	out = nil
+	Parse(json, ReportComments, func(start, end, info int) int {
+		if info&Comment == Comment {
+			out = append(out, string(json[start:end]))
+		}
+		if info&Open == Open && info&Array == Array {
+			return -1
+		}
+		return 1
+	})
+	if len(out) != 6 {
+		t.Fatalf("expected 6 comments, got %q", out)
+	}
+
+	// Comments split across chunks.
+	var evs1 []streamEvent
+	Parse(json, ReportComments, func(start, end, info int) int {
+		evs1 = append(evs1, streamEvent{start, end, info,
+			string(json[start:end])})
+		return 1
+	})
+	var evs2 []streamEvent
+	p := NewParser(ReportComments,
+		func(start, end, info int, token []byte) int {
+			evs2 = append(evs2, streamEvent{start, end, info, string(token)})
+			return 1
+		})
+	for i := 0; i < len(json); i++ {
+		p.Write(json[i : i+1])
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+		t.Fatalf("expected %v, got %v", evs1, evs2)
+	}
+}
+
+func TestCommentErrors(t *testing.T) {
+	for _, tc := range []struct {
+		json   string
+		kind   ErrorKind
+		offset int
+	}{
+		{"[1 /* two ]", UnterminatedComment, 11},
+		{"[1 /* two *", UnterminatedComment, 11},
+		{"[1 /", UnexpectedEOF, 4},
+		{"[1 /x", UnexpectedChar, 4},
+		{"[1 // two ]", UnexpectedEOF, 11},
+		{"/**/ /**/ x", UnexpectedChar, 10},
+	} {
+		_, err := ParseErr([]byte(tc.json), AllowComments, nil)
+		serr, ok := err.(*SyntaxError)
+		if !ok || serr.Kind != tc.kind || serr.Offset != tc.offset {
+			t.Fatalf("%q: expected %v at %d, got %v", tc.json, tc.kind,
+				tc.offset, err)
+		}
+		p := NewParser(AllowComments, nil)
+		p.Write([]byte(tc.json))
+		if err := p.Close(); err.Error() != serr.Error() {
+			t.Fatalf("%q: expected %v, got %v", tc.json, serr, err)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import "io"
+
+// Token is a JSON element along with information about where the element is
+// in the document.
+type Token struct {
+	Start int // start index of the element
+	End   int // end index of the element, such that json[Start:End]
+	Info  int // bit flags, see the info param of Parse
+	// Depth is the nesting depth of the element. The root value is at depth
+	// zero and the elements that are directly inside of an Object or Array
+	// are one deeper than the Object or Array. The Open and Close elements of
+	// an Object or Array are at the same depth as the Object or Array.
+	Depth int
+	// OpenStart is the start index of the Open element of an Object or
+	// Array, for both its Open and Close elements, such that
+	// json[OpenStart:End] is the entire Object or Array for a Close element.
+	// It's zero for other elements.
+	OpenStart int
+	// Index is the zero-based index of an element that is directly in an
+	// Array, which is the same for the Open and Close elements of an Object or
+	// Array. It's -1 for all other elements.
+	Index int
+	// Count is the number of members of an Object, or the number of elements
+	// of an Array, for its Close element. It's zero for other elements.
+	Count int
+}
+
+// ParseTokens parses JSON like ParseErr, but the 'iter' function receives a
+// Token for each element, which has extra information, such as the nesting
+// depth of the element.
+// The return value of 'iter' works the same as it does for Parse, and the
+// depth remains correct for the elements that follow a skipped Object or
+// Array.
+func ParseTokens[T Input](json T, opts int, iter func(tok Token) int) (int,
+	error,
+) {
+	var s stream[T]
+	s.titer = iter
+	n := s.parse(json, opts)
+	if n < 0 {
+		n = -n
+	}
+	return n, s.err()
+}
+
+// Tokenizer returns the elements of a JSON document one at a time, as an
+// alternative to the 'iter' function of Parse.
+//
+//	t := NewTokenizer(json, 0)
+//	for {
+//	    tok, err := t.Next()
+//	    if err == io.EOF {
+//	        break
+//	    }
+//	    if err != nil {
+//	        return err
+//	    }
+//	    fmt.Printf("%s\n", json[tok.Start:tok.End])
+//	}
+//
+// The Tokenizer does not allocate memory for each Token.
+type Tokenizer[T Input] struct {
+	s      stream[T]
+	last   Token // last Token returned by Next
+	peeked bool  // the next Token was scanned by Peek
+	peek   Token // the next Token, when peeked
+	err    error // error, once finished
+}
+
+// NewTokenizer returns a Tokenizer for the JSON document. See Parse for
+// details about the 'opts' param.
+func NewTokenizer[T Input](json T, opts int) *Tokenizer[T] {
+	t := new(Tokenizer[T])
+	t.s.opts = opts
+	t.s.buf = json
+	t.s.eof = true
+	return t
+}
+
+// SetLimits sets the resource limits for the document, which must be done
+// before the first call to Next.
+func (t *Tokenizer[T]) SetLimits(limits Limits) {
+	t.s.limits = limits
+	t.s.truncate()
+}
+
+// Next returns the next element in the document.
+// It returns io.EOF when the document is complete, or a *SyntaxError when the
+// document is invalid. Once Next returns an error, all subsequent calls return
+// the same error.
+func (t *Tokenizer[T]) Next() (Token, error) {
+	if t.peeked {
+		t.peeked = false
+		t.last = t.peek
+		return t.peek, nil
+	}
+	if t.err != nil {
+		return Token{}, t.err
+	}
+	start, end, info, status := t.s.event()
+	if status != stEvent {
+		t.fail(status)
+		return Token{}, t.err
+	}
+	t.last = t.s.token(start, end, info)
+	return t.last, nil
+}
+
+// Peek returns the next element in the document without consuming it. It
+// returns the same values that the next call to Next will return.
+func (t *Tokenizer[T]) Peek() (Token, error) {
+	if t.peeked {
+		return t.peek, nil
+	}
+	if t.err != nil {
+		return Token{}, t.err
+	}
+	start, end, info, status := t.s.event()
+	if status != stEvent {
+		t.fail(status)
+		return Token{}, t.err
+	}
+	t.peek = t.s.token(start, end, info)
+	t.peeked = true
+	return t.peek, nil
+}
+
+// fail sets the error for the stream status.
+func (t *Tokenizer[T]) fail(status int) {
+	if status == stDone {
+		t.err = io.EOF
+	} else {
+		t.err = t.s.err()
+	}
+}
+
+// Skip skips the rest of the Object or Array that was opened by the last
+// element returned by Next, including its Close element, which is the same
+// as returning -1 from the 'iter' function of Parse for the Open element,
+// except that the Close element is also skipped. For all other elements,
+// Skip does nothing.
+// Skipping is faster than calling Next for each of the skipped elements.
+func (t *Tokenizer[T]) Skip() error {
+	if t.last.Info&Open == 0 {
+		return nil
+	}
+	depth := t.last.Depth
+	t.last = Token{}
+	s := &t.s
+	max := s.limits.maxDepth()
+	if !t.peeked && t.err == nil && s.opts == 0 && max >= 0 &&
+		s.limits == (Limits{MaxDepth: s.limits.MaxDepth}) {
+		// Validate the children with the same functions as Parse, which
+		// leaves the stream at the Close element. An invalid document is
+		// scanned again with the stream to find out why. Without a depth
+		// limit, the children are skipped with the stream, which has no
+		// recursion.
+		var i int
+		var ok bool
+		if s.stack[depth] == '{' {
+			i, ok, _ = vobject(s.buf, s.i, nil, max-depth-1)
+		} else {
+			i, ok, _ = varray(s.buf, s.i, nil, max-depth-1)
+		}
+		if ok {
+			s.i = i - 1
+		}
+	}
+	for {
+		tok, err := t.Next()
+		if err != nil {
+			return err
+		}
+		if tok.Info&Close == Close && tok.Depth == depth {
+			return nil
+		}
+	}
+}
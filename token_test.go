@@ -0,0 +1,283 @@
+package pjson
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestParseTokensDepth(t *testing.T) {
+	json := []byte(`{"a":[1,{"b":2}],"c":{"d":[]}}`)
+	var out []string
+	n, err := ParseTokens(json, 0, func(tok Token) int {
+		out = append(out, fmt.Sprintf("%s%d", json[tok.Start:tok.End],
+			tok.Depth))
+		return 1
+	})
+	if n != len(json) || err != nil {
+		t.Fatalf("expected %d, got %d %v", len(json), n, err)
+	}
+	expect := `[{0 "a"1 :1 [1 12 ,2 {2 "b"3 :3 23 }2 ]1 ,1 "c"1 :1 {1 "d"2 :2 ` +
+		`[2 ]2 }1 }0]`
+	if fmt.Sprint(out) != expect {
+		t.Fatalf("expected %s, got %s", expect, out)
+	}
+
+	// Skipped children and comments.
+	json = []byte(`[[1,[2]], /* c */ {"a":[3]}, 4]`)
+	out = nil
+	ParseTokens(json, ReportComments, func(tok Token) int {
+		out = append(out, fmt.Sprintf("%s%d", json[tok.Start:tok.End],
+			tok.Depth))
+		if tok.Info&(Array|Open) == Array|Open && tok.Depth == 1 {
+			return -1
+		}
+		return 1
+	})
+	expect = `[[0 [1 ]1 ,1 /* c */1 {1 "a"2 :2 [2 33 ]2 }1 ,1 41 ]0]`
+	if fmt.Sprint(out) != expect {
+		t.Fatalf("expected %s, got %s", expect, out)
+	}
+
+	// Early stop.
+	n, err = ParseTokens(json, AllowComments, func(tok Token) int {
+		if tok.Depth == 2 {
+			return 0
+		}
+		return 1
+	})
+	if n != 3 || err != nil {
+		t.Fatalf("expected 3, got %d %v", n, err)
+	}
+
+	// Same elements as Parse.
+	for _, doc := range streamDocs {
+		json := []byte(doc)
+		evs1, n1 := collectParse(json, func(info int) int { return 1 })
+		var evs2 []streamEvent
+		n2, err := ParseTokens(json, 0, func(tok Token) int {
+			evs2 = append(evs2, streamEvent{tok.Start, tok.End, tok.Info,
+				string(json[tok.Start:tok.End])})
+			return 1
+		})
+		if (err == nil) != (n1 > 0) || n1 > 0 && n1 != n2 ||
+			n1 <= 0 && n1 != -n2 {
+			t.Fatalf("%q: expected %d, got %d %v", json, n1, n2, err)
+		}
+		if fmt.Sprint(evs1) != fmt.Sprint(evs2) {
+			t.Fatalf("%q: expected %v, got %v", json, evs1, evs2)
+		}
+	}
+}
+
+func collectTokens(t *Tokenizer[[]byte], json []byte) ([]string, error) {
+	var out []string
+	for {
+		tok, err := t.Next()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, fmt.Sprintf("%s%d", json[tok.Start:tok.End],
+			tok.Depth))
+	}
+}
+
+func TestTokenizer(t *testing.T) {
+	// Same elements and errors as ParseTokens.
+	docs := append(streamDocs, json5Doc, `[1,/*x*/2]`, string(json1),
+		string(json2))
+	for _, doc := range docs {
+		json := []byte(doc)
+		for _, opts := range []int{0, AllowJSON5 | ReportComments} {
+			var out1 []string
+			_, err1 := ParseTokens(json, opts, func(tok Token) int {
+				out1 = append(out1, fmt.Sprintf("%s%d",
+					json[tok.Start:tok.End], tok.Depth))
+				return 1
+			})
+			out2, err2 := collectTokens(NewTokenizer(json, opts), json)
+			if fmt.Sprint(out1, err1) != fmt.Sprint(out2, err2) {
+				t.Fatalf("%q: expected %v %v, got %v %v", json, out1, err1,
+					out2, err2)
+			}
+		}
+	}
+
+	// Peek returns the same values as Next.
+	json := []byte(`{"a":[1,2]}`)
+	tk := NewTokenizer(json, 0)
+	for {
+		tok1, err1 := tk.Peek()
+		tok2, err2 := tk.Peek()
+		tok3, err3 := tk.Next()
+		if tok1 != tok2 || tok1 != tok3 || err1 != err2 || err1 != err3 {
+			t.Fatalf("expected %v %v, got %v %v and %v %v", tok1, err1,
+				tok2, err2, tok3, err3)
+		}
+		if err1 != nil {
+			if err1 != io.EOF {
+				t.Fatal(err1)
+			}
+			break
+		}
+	}
+	if _, err := tk.Next(); err != io.EOF {
+		t.Fatalf("expected EOF, got %v", err)
+	}
+}
+
+func testskip(t *testing.T, json string, opts int, limits Limits,
+	peek bool, expect string,
+) {
+	t.Helper()
+	tk := NewTokenizer([]byte(json), opts)
+	tk.SetLimits(limits)
+	var out []string
+	var err error
+	for {
+		var tok Token
+		tok, err = tk.Next()
+		if err != nil {
+			break
+		}
+		out = append(out, json[tok.Start:tok.End])
+		if tok.Info&Open == Open && tok.Depth == 1 {
+			if peek {
+				tk.Peek()
+			}
+			if err = tk.Skip(); err != nil {
+				break
+			}
+		}
+	}
+	if err == io.EOF {
+		err = nil
+	}
+	if fmt.Sprint(out, err) != expect {
+		t.Fatalf("%q: expected %s, got %v", json, expect, fmt.Sprint(out, err))
+	}
+}
+
+func TestTokenizerSkip(t *testing.T) {
+	json := `{"a":{"b":[1,2]},"c":[3,{"d":4}],"e":[]}`
+	expect := `[{ "a" : { , "c" : [ , "e" : [ }] <nil>`
+	for _, opts := range []int{0, AllowJSON5} {
+		testskip(t, json, opts, Limits{}, false, expect)
+		testskip(t, json, opts, Limits{}, true, expect)
+		testskip(t, json, opts, Limits{MaxTokens: 100}, false, expect)
+	}
+	testskip(t, `[1,[2,[3]],4]`, 0, Limits{}, false, `[[ 1 , [ , 4 ]] <nil>`)
+
+	// Skipped children are still validated.
+	json = `{"a":{"b":[1,2}},"c":1}`
+	_, err := ParseErr([]byte(json), 0, nil)
+	for _, opts := range []int{0, AllowJSON5} {
+		testskip(t, json, opts, Limits{}, false,
+			fmt.Sprint([]string{`{`, `"a"`, `:`, `{`}, err))
+	}
+	testskip(t, `[[[[1]]]]`, 0, Limits{MaxDepth: 3}, false,
+		`[[ [] pjson: nesting depth exceeded '[' at line 1, column 4 `+
+			`(offset 3)`)
+	testskip(t, `[[[1]]]`, 0, Limits{MaxDepth: 3}, false, `[[ [ ]] <nil>`)
+	testskip(t, `[[1,2,3]]`, 0, Limits{MaxMembers: 2}, false,
+		`[[ [] pjson: too many members '3' at line 1, column 7 (offset 6)`)
+}
+
+func TestTokenizerAllocs(t *testing.T) {
+	// The allocations do not depend on the number of tokens.
+	allocs := func(n int) float64 {
+		json := []byte(`[` + strings.Repeat(`{"a":[1,"b",true]},`, n) + `1]`)
+		return testing.AllocsPerRun(10, func() {
+			tk := NewTokenizer(json, 0)
+			for {
+				if _, err := tk.Next(); err != nil {
+					if err != io.EOF {
+						t.Fatal(err)
+					}
+					break
+				}
+			}
+		})
+	}
+	if a, b := allocs(1), allocs(1000); a != b {
+		t.Fatalf("expected %.0f allocations, got %.0f", a, b)
+	}
+}
+
+func TestTokenOpenStart(t *testing.T) {
+	json := []byte(`{"a":[1,{"b":2}],"c":{"d":[]}}`)
+	var out []string
+	for _, skip := range []bool{false, true} {
+		out = out[:0]
+		ParseTokens(json, 0, func(tok Token) int {
+			if tok.Info&(Open|Close) == 0 {
+				if tok.OpenStart != 0 {
+					t.Fatalf("expected 0, got %d", tok.OpenStart)
+				}
+				return 1
+			}
+			if tok.Info&Open == Open {
+				if tok.OpenStart != tok.Start {
+					t.Fatalf("expected %d, got %d", tok.Start, tok.OpenStart)
+				}
+				if skip && tok.Depth == 1 {
+					return -1
+				}
+				return 1
+			}
+			out = append(out, string(json[tok.OpenStart:tok.End]))
+			return 1
+		})
+		expect := `[{"b":2} [1,{"b":2}] [] {"d":[]} ` +
+			`{"a":[1,{"b":2}],"c":{"d":[]}}]`
+		if skip {
+			expect = `[[1,{"b":2}] {"d":[]} {"a":[1,{"b":2}],"c":{"d":[]}}]`
+		}
+		if fmt.Sprint(out) != expect {
+			t.Fatalf("expected %s, got %v", expect, out)
+		}
+	}
+
+	// Tokenizer
+	tk := NewTokenizer([]byte(` [[], {}] `), 0)
+	out = out[:0]
+	for {
+		tok, err := tk.Next()
+		if err != nil {
+			break
+		}
+		out = append(out, fmt.Sprintf("%d-%d", tok.OpenStart, tok.End))
+	}
+	if fmt.Sprint(out) != `[1-2 2-3 2-4 0-5 6-7 6-8 1-9]` {
+		t.Fatalf("got %v", out)
+	}
+}
+
+func TestTokenIndexCount(t *testing.T) {
+	json := []byte(`[1,[2,3],{"a":[],"b":4},[]]`)
+	for _, skip := range []bool{false, true} {
+		var out []string
+		ParseTokens(json, 0, func(tok Token) int {
+			if tok.Info&(Comma|Colon) == 0 {
+				out = append(out, fmt.Sprintf("%s%d/%d",
+					json[tok.Start:tok.End], tok.Index, tok.Count))
+			}
+			if skip && tok.Info&Open == Open && tok.Depth == 1 {
+				return -1
+			}
+			return 1
+		})
+		expect := `[[-1/0 10/0 [1/0 20/0 31/0 ]1/2 {2/0 "a"-1/0 [-1/0 ]-1/0 ` +
+			`"b"-1/0 4-1/0 }2/2 [3/0 ]3/0 ]-1/4]`
+		if skip {
+			expect = `[[-1/0 10/0 [1/0 ]1/2 {2/0 }2/2 [3/0 ]3/0 ]-1/4]`
+		}
+		if fmt.Sprint(out) != expect {
+			t.Fatalf("expected %s, got %v", expect, out)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+import (
+	"errors"
+	"unicode/utf8"
+)
+
+// ErrLoneSurrogate is returned when unescaping a String with a \u escape that
+// is an unpaired UTF-16 surrogate, and the RejectLoneSurrogates mode is used.
+var ErrLoneSurrogate = errors.New("pjson: lone surrogate")
+
+// UnescapeMode determines how unpaired UTF-16 surrogates in \u escapes, such
+// as "\ud800", are unescaped.
+type UnescapeMode int
+
+const (
+	// ReplaceLoneSurrogates replaces unpaired surrogates with the Unicode
+	// replacement character U+FFFD, like encoding/json does.
+	ReplaceLoneSurrogates UnescapeMode = iota
+	// RejectLoneSurrogates fails with ErrLoneSurrogate on unpaired
+	// surrogates.
+	RejectLoneSurrogates
+)
+
+// AppendUnescaped appends the unescaped contents of a String token to dst,
+// without the quotes, and returns the extended buffer.
+// The token must be the complete String element, such as json[start:end] when
+// the 'info' from Parse has the String bit set. Unpaired UTF-16 surrogates are
+// replaced with U+FFFD.
+// When the token has no escape characters, which is always the case when the
+// 'info' does not have the Escaped bit, the quotes are simply stripped.
+// Returns ErrInvalid if the token is not a valid String.
+func AppendUnescaped[T Input](dst []byte, token T) ([]byte, error) {
+	return AppendUnescapedMode(dst, token, ReplaceLoneSurrogates)
+}
+
+// Unescape returns the unescaped contents of a String token.
+// See AppendUnescaped for more information.
+func Unescape[T Input](token T) (string, error) {
+	if len(token) >= 2 && indexbyte(token, '\\') == -1 &&
+		(token[0] == '"' || token[0] == '\'') &&
+		token[len(token)-1] == token[0] {
+		return string(token[1 : len(token)-1]), nil
+	}
+	dst, err := AppendUnescaped(nil, token)
+	if err != nil {
+		return "", err
+	}
+	return string(dst), nil
+}
+
+// AppendUnescapedMode works like AppendUnescaped, but it allows for choosing
+// how unpaired UTF-16 surrogates are handled.
+// Along with the standard JSON escapes, the JSON5 escapes are also
+// unescaped, which allows for unescaping any String token that was parsed
+// with the AllowJSON5 option.
+func AppendUnescapedMode[T Input](dst []byte, token T, mode UnescapeMode,
+) ([]byte, error) {
+	if len(token) < 2 || (token[0] != '"' && token[0] != '\'') ||
+		token[len(token)-1] != token[0] {
+		return dst, ErrInvalid
+	}
+	mark := len(dst)
+	str := token[1 : len(token)-1]
+	for {
+		i := indexbyte(str, '\\')
+		if i == -1 {
+			return append(dst, str...), nil
+		}
+		dst = append(dst, str[:i]...)
+		str = str[i+1:]
+		if len(str) == 0 {
+			return dst[:mark], ErrInvalid
+		}
+		ch := str[0]
+		str = str[1:]
+		switch ch {
+		case 'b':
+			dst = append(dst, '\b')
+		case 'f':
+			dst = append(dst, '\f')
+		case 'n':
+			dst = append(dst, '\n')
+		case 'r':
+			dst = append(dst, '\r')
+		case 't':
+			dst = append(dst, '\t')
+		case 'v':
+			dst = append(dst, '\v')
+		case '0':
+			dst = append(dst, 0)
+		case '\n':
+			// JSON5 line continuation
+		case '\r':
+			// JSON5 line continuation, which may be "\r\n"
+			if len(str) > 0 && str[0] == '\n' {
+				str = str[1:]
+			}
+		case 'x', 'u':
+			n := 2
+			if ch == 'u' {
+				n = 4
+			}
+			r, ok := unhex(str, n)
+			if !ok {
+				return dst[:mark], ErrInvalid
+			}
+			str = str[n:]
+			if r >= 0xD800 && r < 0xE000 && ch == 'u' {
+				// UTF-16 surrogate
+				r2 := rune(-1)
+				if r < 0xDC00 && len(str) >= 6 && str[0] == '\\' &&
+					str[1] == 'u' {
+					r2, _ = unhex(str[2:], 4)
+				}
+				if r2 >= 0xDC00 && r2 < 0xE000 {
+					r = ((r - 0xD800) << 10) + (r2 - 0xDC00) + 0x10000
+					str = str[6:]
+				} else if mode == RejectLoneSurrogates {
+					return dst[:mark], ErrLoneSurrogate
+				} else {
+					r = utf8.RuneError
+				}
+			}
+			var buf [4]byte
+			dst = append(dst, buf[:utf8.EncodeRune(buf[:], r)]...)
+		case 0xE2:
+			if len(str) >= 2 && str[0] == 0x80 &&
+				(str[1] == 0xA8 || str[1] == 0xA9) {
+				// JSON5 line continuation with U+2028 or U+2029
+				str = str[2:]
+			} else {
+				dst = append(dst, ch)
+			}
+		default:
+			// The character itself, such as '"', '\\', '/', or any JSON5
+			// identity escape.
+			if ch >= '1' && ch <= '9' {
+				return dst[:mark], ErrInvalid
+			}
+			dst = append(dst, ch)
+		}
+	}
+}
+
+// indexbyte returns the index of the first 'c' in 'str', or -1 if there is
+// none.
+func indexbyte[T Input](str T, c byte) int {
+	for i := 0; i < len(str); i++ {
+		if str[i] == c {
+			return i
+		}
+	}
+	return -1
+}
+
+// unhex returns the rune for the first 'n' hex digits in 'str'.
+func unhex[T Input](str T, n int) (rune, bool) {
+	if len(str) < n {
+		return 0, false
+	}
+	var r rune
+	for i := 0; i < n; i++ {
+		ch := str[i]
+		switch {
+		case ch >= '0' && ch <= '9':
+			ch -= '0'
+		case ch >= 'a' && ch <= 'f':
+			ch -= 'a' - 10
+		case ch >= 'A' && ch <= 'F':
+			ch -= 'A' - 10
+		default:
+			return 0, false
+		}
+		r = r<<4 | rune(ch)
+	}
+	return r, true
+}
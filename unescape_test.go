@@ -0,0 +1,122 @@
+package pjson
+
+import (
+	"encoding/json"
+	"math/rand"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func testunescape(t *testing.T, token, expect string, experr error) {
+	t.Helper()
+	str, err := Unescape([]byte(token))
+	if err != experr || str != expect {
+		t.Fatalf("%s: expected %q %v, got %q %v", token, expect, experr,
+			str, err)
+	}
+}
+
+func TestUnescape(t *testing.T) {
+	testunescape(t, `""`, "", nil)
+	testunescape(t, `"hello"`, "hello", nil)
+	testunescape(t, `"hi\nthere"`, "hi\nthere", nil)
+	testunescape(t, `"\"\\\/\b\f\n\r\t"`, "\"\\/\b\f\n\r\t", nil)
+	testunescape(t, `"\u0041\u00e9\u4E16"`, "Aé世", nil)
+	testunescape(t, `"\ud83d\ude00!"`, "😀!", nil)
+	testunescape(t, `"\uD83D\uDE00"`, "😀", nil)
+	testunescape(t, `"\ud800"`, "�", nil)
+	testunescape(t, `"\udc00\ud800"`, "��", nil)
+	testunescape(t, `"\ud800A"`, "�A", nil)
+	testunescape(t, `"\ude00\ud83d"`, "��", nil)
+	testunescape(t, `"\ud83d\ud83d\ude00"`, "�😀", nil)
+	testunescape(t, `'it\'s'`, "it's", nil)
+	testunescape(t, `'\x41\v\0'`, "A\v\x00", nil)
+	testunescape(t, "'a\\\nb\\\r\nc\\ d'", "abcd", nil)
+	testunescape(t, `"\u004"`, "", ErrInvalid)
+	testunescape(t, `"\1"`, "", ErrInvalid)
+	testunescape(t, `"abc\"`, "", ErrInvalid)
+	testunescape(t, `"abc`, "", ErrInvalid)
+	testunescape(t, `abc`, "", ErrInvalid)
+	testunescape(t, `"`, "", ErrInvalid)
+	testunescape(t, `'abc"`, "", ErrInvalid)
+
+	dst := []byte("prefix:")
+	dst, err := AppendUnescapedMode(dst, []byte(`"a\ud800"`),
+		RejectLoneSurrogates)
+	if err != ErrLoneSurrogate || string(dst) != "prefix:" {
+		t.Fatalf("expected %v, got %q %v", ErrLoneSurrogate, dst, err)
+	}
+	dst, err = AppendUnescapedMode(dst, []byte(`"a\ud83d\ude00"`),
+		RejectLoneSurrogates)
+	if err != nil || string(dst) != "prefix:a😀" {
+		t.Fatalf("expected nil, got %q %v", dst, err)
+	}
+}
+
+func TestUnescapeStdlib(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+	for i := 0; i < 10000; i++ {
+		runes := make([]rune, rand.Intn(10))
+		for j := range runes {
+			switch rand.Intn(4) {
+			case 0:
+				runes[j] = rune(rand.Intn(0x80))
+			case 1:
+				runes[j] = rune(rand.Intn(0x10000))
+			case 2:
+				runes[j] = rune(0xD800 + rand.Intn(0x800))
+			case 3:
+				runes[j] = rune(rand.Intn(utf8.MaxRune))
+			}
+		}
+		token, _ := json.Marshal(string(runes))
+		// Escape everything with \u, using surrogates when needed.
+		var escaped []byte
+		escaped = append(escaped, '"')
+		for _, r := range string(runes) {
+			if r > 0xFFFF {
+				r -= 0x10000
+				escaped = appendU(escaped, 0xD800+(r>>10))
+				escaped = appendU(escaped, 0xDC00+(r&0x3FF))
+			} else {
+				escaped = appendU(escaped, r)
+			}
+		}
+		escaped = append(escaped, '"')
+		for _, token := range [][]byte{token, escaped} {
+			var expect string
+			if err := json.Unmarshal(token, &expect); err != nil {
+				t.Fatal(err)
+			}
+			str, err := Unescape(token)
+			if err != nil || str != expect {
+				t.Fatalf("%s: expected %q, got %q %v", token, expect, str, err)
+			}
+		}
+	}
+}
+
+func appendU(dst []byte, r rune) []byte {
+	const hex = "0123456789abcdef"
+	return append(dst, '\\', 'u', hex[r>>12&15], hex[r>>8&15], hex[r>>4&15],
+		hex[r&15])
+}
+
+func TestUnescapeAllocs(t *testing.T) {
+	dst := make([]byte, 0, 64)
+	tokens := [][]byte{[]byte(`"hello world"`),
+		[]byte(`"hello\nworld\ud83d\ude00\u00e9"`)}
+	allocs := testing.AllocsPerRun(100, func() {
+		for _, token := range tokens {
+			var err error
+			dst, err = AppendUnescaped(dst[:0], token)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected zero allocations, got %.0f", allocs)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2021 Joshua J Baker. All rights reserved.
+// Use of this source code is governed by an MIT-style
+// license that can be found in the LICENSE file.
+
+package pjson
+
+// Valid returns true if the JSON document is valid.
+// It's the same as checking that Parse returns a positive value with a nil
+// 'iter' function, but it's faster because there are no checks for the
+// 'iter' function.
+func Valid[T Input](json T) bool {
+	i, ok := validdoc(json, 0, DefaultMaxDepth)
+	return ok && i == len(json)
+}
+
+// ValidString returns true if the JSON document is valid. See Valid.
+// It's the same as Valid with a string, and the string is not copied.
+func ValidString(json string) bool {
+	return Valid(json)
+}
+
+// ValidOpts returns true if the JSON document is valid for the 'opts', which
+// are the same as the 'opts' for Parse.
+func ValidOpts[T Input](json T, opts int) bool {
+	if opts == 0 {
+		return Valid(json)
+	}
+	var s stream[T]
+	return s.parse(json, opts) > 0
+}
+
+// The following functions are the same as vdoc, vany, vobject and varray,
+// without the 'iter' function.
+
+func validdoc[T Input](json T, i int, depth int) (outi int, ok bool) {
+	i, ok = validany(json, i, depth)
+	if !ok {
+		return i, false
+	}
+	for ; i < len(json); i++ {
+		if !isws(json[i]) {
+			return i, false
+		}
+	}
+	return i, true
+}
+
+func validany[T Input](json T, i int, depth int) (outi int, ok bool) {
+	for ; i < len(json); i++ {
+		if isws(json[i]) {
+			continue
+		}
+		switch json[i] {
+		case '"':
+			i, _, ok, _ = vstring(json, i+1, &strtoks)
+			return i, ok
+		case '{':
+			if depth == 0 {
+				return i, false
+			}
+			return validobject(json, i+1, depth-1)
+		case '[':
+			if depth == 0 {
+				return i, false
+			}
+			return validarray(json, i+1, depth-1)
+		case '-', '0', '1', '2', '3', '4', '5', '6', '7', '8', '9':
+			i, _, ok, _ = vnumber(json, i+1)
+			return i, ok
+		case 't':
+			i, ok, _ = vtrue(json, i+1)
+			return i, ok
+		case 'f':
+			i, ok, _ = vfalse(json, i+1)
+			return i, ok
+		case 'n':
+			i, ok, _ = vnull(json, i+1)
+			return i, ok
+		}
+		return i, false
+	}
+	return i, false
+}
+
+func validobject[T Input](json T, i int, depth int) (outi int, ok bool) {
+	for ; i < len(json); i++ {
+		if isws(json[i]) {
+			continue
+		}
+		if json[i] == '}' {
+			return i + 1, true
+		}
+		break
+	}
+	for ; i < len(json); i++ {
+		if isws(json[i]) {
+			continue
+		}
+		if json[i] != '"' {
+			return i, false
+		}
+		if i, _, ok, _ = vstring(json, i+1, &strtoks); !ok {
+			return i, false
+		}
+		if i, ok, _ = vcolon(json, i); !ok {
+			return i, false
+		}
+		if i, ok = validany(json, i, depth); !ok {
+			return i, false
+		}
+		if i, ok, _ = vcomma(json, i, '}'); !ok {
+			return i, false
+		}
+		if json[i] == '}' {
+			return i + 1, true
+		}
+	}
+	return i, false
+}
+
+func validarray[T Input](json T, i int, depth int) (outi int, ok bool) {
+	for ; i < len(json); i++ {
+		if isws(json[i]) {
+			continue
+		}
+		if json[i] == ']' {
+			return i + 1, true
+		}
+		break
+	}
+	for ; i < len(json); i++ {
+		if i, ok = validany(json, i, depth); !ok {
+			return i, false
+		}
+		if i, ok, _ = vcomma(json, i, ']'); !ok {
+			return i, false
+		}
+		if json[i] == ']' {
+			return i + 1, true
+		}
+	}
+	return i, false
+}
@@ -0,0 +1,44 @@
+package pjson
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValid(t *testing.T) {
+	docs := append(streamDocs, string(json1), string(json2), ``, ` `, `{`,
+		`{"a"}`, `{"a":}`, `{"a":1,}`, `[1,]`, `[,1]`, `[1 2]`, `{"a" 1}`,
+		`{1:2}`, `"\x"`, `tru`, `nul`, `fals`, `1.`, `-`, `[]]`, `{}}`,
+		` [ 1 , { "a" : [ ] } ] `, strings.Repeat("[", DefaultMaxDepth)+
+			strings.Repeat("]", DefaultMaxDepth),
+		strings.Repeat("[", DefaultMaxDepth+1)+
+			strings.Repeat("]", DefaultMaxDepth+1))
+	for _, doc := range docs {
+		expect := Parse([]byte(doc), 0, nil) > 0
+		if Valid([]byte(doc)) != expect || ValidString(doc) != expect ||
+			ValidOpts([]byte(doc), 0) != expect ||
+			ValidOpts([]byte(doc), AllowComments) != expect {
+			t.Fatalf("%q: expected %t", doc, expect)
+		}
+	}
+	if !ValidOpts([]byte(json5Doc), AllowJSON5) || Valid([]byte(json5Doc)) {
+		t.Fatal("expected only valid with AllowJSON5")
+	}
+}
+
+func TestValidStdlib(t *testing.T) {
+	rand.Seed(time.Now().UnixNano())
+	const chars = ` {}[],:"\-.0123456789eEtruefalsnl`
+	for i := 0; i < 100000; i++ {
+		b := make([]byte, rand.Intn(12))
+		for j := range b {
+			b[j] = chars[rand.Intn(len(chars))]
+		}
+		if Valid(b) != json.Valid(b) {
+			t.Fatalf("%q: expected %t", b, json.Valid(b))
+		}
+	}
+}